@@ -21,6 +21,7 @@ require (
 	github.com/df-mc/goleveldb v1.1.9
 	github.com/dop251/goja v0.0.0-20251201205617-2bb4c724c0f9
 	github.com/dop251/goja_nodejs v0.0.0-20251015164255-5e94316bedaf
+	github.com/evanw/esbuild v0.25.0
 	github.com/fatih/color v1.18.0
 	github.com/gioui-plugins/gio-plugins v0.8.0
 	github.com/go-gl/mathgl v1.2.0
@@ -3,6 +3,7 @@ package handlers
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -14,7 +15,7 @@ import (
 
 	"github.com/bedrock-tool/bedrocktool/utils"
 	"github.com/bedrock-tool/bedrocktool/utils/proxy"
-	"github.com/klauspost/compress/s2"
+	"github.com/bedrock-tool/bedrocktool/utils/proxy/pcap2"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 	"github.com/sirupsen/logrus"
@@ -22,20 +23,7 @@ import (
 
 func (p *packetCapturer) dumpPacket(toServer bool, payload []byte, timeReceived time.Time) {
 	p.dumpLock.Lock()
-	payloadCompressed := s2.EncodeBetter(nil, payload)
-
-	var buf []byte = []byte{0xAA, 0xAA, 0xAA, 0xAA}
-	packetSize := uint32(len(payloadCompressed))
-	buf = binary.LittleEndian.AppendUint32(buf, packetSize)
-	if toServer {
-		buf = append(buf, 1)
-	} else {
-		buf = append(buf, 0)
-	}
-	buf = binary.LittleEndian.AppendUint64(buf, uint64(timeReceived.UnixMilli()))
-	buf = append(buf, payloadCompressed...)
-	buf = append(buf, []byte{0xBB, 0xBB, 0xBB, 0xBB}...)
-	p.wPacket.Write(buf)
+	p.wPacket.Write(pcap2.AppendPacket(nil, toServer, payload, timeReceived))
 	p.dumpLock.Unlock()
 }
 
@@ -66,7 +54,7 @@ func (p *packetCapturer) OnServerConnect(s *proxy.Session) (disconnect bool, err
 	}
 
 	p.file.WriteString("BTCP")
-	binary.Write(p.file, binary.LittleEndian, uint32(5))
+	binary.Write(p.file, binary.LittleEndian, uint32(pcap2.Version))
 	binary.Write(p.file, binary.LittleEndian, uint64(0))
 
 	z := zip.NewWriter(p.file)
@@ -166,4 +154,7 @@ func NewPacketCapturer() *proxy.Handler {
 
 func init() {
 	proxy.NewPacketCapturer = NewPacketCapturer
+	proxy.RegisterHandler("capture", "Packet Capturer", func(ctx context.Context) *proxy.Handler {
+		return NewPacketCapturer()
+	})
 }
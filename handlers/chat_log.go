@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sync"
@@ -36,7 +37,7 @@ func NewChatLogger() func() *proxy.Handler {
 	return func() *proxy.Handler {
 		c := &chatLogger{}
 		return &proxy.Handler{
-			Name:           "Packet Capturer",
+			Name:           "Chat Logger",
 			PacketCallback: c.PacketCB,
 			SessionStart: func(s *proxy.Session, serverName string) error {
 				filename := fmt.Sprintf("%s_%s_chat.log", serverName, time.Now().Format("2006-01-02_15-04-05_Z07"))
@@ -54,3 +55,9 @@ func NewChatLogger() func() *proxy.Handler {
 	}
 
 }
+
+func init() {
+	proxy.RegisterHandler("chat", "Chat Logger", func(ctx context.Context) *proxy.Handler {
+		return NewChatLogger()()
+	})
+}
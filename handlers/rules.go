@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/proxy"
+	"github.com/bedrock-tool/bedrocktool/utils/proxy/pcap2"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// rules file format, json works too since it is valid yaml
+//
+//	rules:
+//	  - packet: SetTitle
+//	    drop: true
+//	  - packet: Text
+//	    direction: client
+//	    match:
+//	      Message: "~(?i)discord\.gg"
+//	    replace:
+//	      Message: {pattern: "discord\\.gg/\\w+", with: "[link]"}
+//	  - packet: Transfer
+//	    drop: true
+//	    inject:
+//	      - packet: Text
+//	        direction: client
+//	        fields: {TextType: 1, Message: "blocked a transfer"}
+type rulesFile struct {
+	Rules []*packetRule `yaml:"rules"`
+}
+
+type packetRule struct {
+	// name or id of the packet
+	Packet string `yaml:"packet"`
+	// where the packet is going, "client" or "server", both if empty
+	Direction string `yaml:"direction"`
+	// field paths like "Position.X" to values, strings starting with ~ are regular expressions
+	Match   map[string]any         `yaml:"match"`
+	Drop    bool                   `yaml:"drop"`
+	Delay   string                 `yaml:"delay"`
+	Set     map[string]any         `yaml:"set"`
+	Replace map[string]replaceRule `yaml:"replace"`
+	Inject  []injectRule           `yaml:"inject"`
+
+	id       uint32
+	delay    time.Duration
+	patterns map[string]*regexp.Regexp
+}
+
+type replaceRule struct {
+	Pattern string `yaml:"pattern"`
+	With    string `yaml:"with"`
+
+	re *regexp.Regexp
+}
+
+type injectRule struct {
+	Packet string `yaml:"packet"`
+	// defaults to the direction of the matched packet
+	Direction string         `yaml:"direction"`
+	Fields    map[string]any `yaml:"fields"`
+
+	data []byte
+}
+
+// fieldByPath looks up a dot separated field path in a packet
+func fieldByPath(pk packet.Packet, path string) (reflect.Value, error) {
+	v := reflect.ValueOf(pk)
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, fmt.Errorf("%s is nil", path)
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("%s is not a struct field", path)
+		}
+		v = v.FieldByName(name)
+		if !v.IsValid() {
+			return reflect.Value{}, fmt.Errorf("%T has no field %s", pk, path)
+		}
+	}
+	return v, nil
+}
+
+func checkDirection(direction string) error {
+	switch direction {
+	case "", "client", "server":
+		return nil
+	}
+	return fmt.Errorf("invalid direction %s, has to be 'client' or 'server'", direction)
+}
+
+func (r *packetRule) prepare() error {
+	pk, ok := pcap2.NewPacketByName(r.Packet)
+	if !ok {
+		return fmt.Errorf("unknown packet %s", r.Packet)
+	}
+	r.id = pk.ID()
+	if err := checkDirection(r.Direction); err != nil {
+		return err
+	}
+
+	if r.Delay != "" {
+		var err error
+		if r.delay, err = time.ParseDuration(r.Delay); err != nil {
+			return err
+		}
+	}
+
+	r.patterns = make(map[string]*regexp.Regexp)
+	for path, value := range r.Match {
+		if _, err := fieldByPath(pk, path); err != nil {
+			return err
+		}
+		if s, ok := value.(string); ok && strings.HasPrefix(s, "~") {
+			re, err := regexp.Compile(s[1:])
+			if err != nil {
+				return err
+			}
+			r.patterns[path] = re
+		}
+	}
+	for path := range r.Set {
+		if _, err := fieldByPath(pk, path); err != nil {
+			return err
+		}
+	}
+	for path, replace := range r.Replace {
+		field, err := fieldByPath(pk, path)
+		if err != nil {
+			return err
+		}
+		if field.Kind() != reflect.String {
+			return fmt.Errorf("%s is not a string", path)
+		}
+		if replace.re, err = regexp.Compile(replace.Pattern); err != nil {
+			return err
+		}
+		r.Replace[path] = replace
+	}
+
+	for i, inject := range r.Inject {
+		injectPk, ok := pcap2.NewPacketByName(inject.Packet)
+		if !ok {
+			return fmt.Errorf("unknown packet %s", inject.Packet)
+		}
+		if err := checkDirection(inject.Direction); err != nil {
+			return err
+		}
+		data, err := json.Marshal(inject.Fields)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(data, injectPk); err != nil {
+			return fmt.Errorf("inject %s: %w", inject.Packet, err)
+		}
+		r.Inject[i].data = data
+	}
+	return nil
+}
+
+func (r *packetRule) matches(pk packet.Packet, toServer bool) bool {
+	if pk.ID() != r.id {
+		return false
+	}
+	if r.Direction == "server" && !toServer || r.Direction == "client" && toServer {
+		return false
+	}
+	for path, value := range r.Match {
+		field, err := fieldByPath(pk, path)
+		if err != nil {
+			return false
+		}
+		fieldString := fmt.Sprint(field.Interface())
+		if re, ok := r.patterns[path]; ok {
+			if !re.MatchString(fieldString) {
+				return false
+			}
+		} else if fieldString != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// modify applies set and replace to the packet
+func (r *packetRule) modify(pk packet.Packet) error {
+	for path, value := range r.Set {
+		field, err := fieldByPath(pk, path)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(data, field.Addr().Interface()); err != nil {
+			return fmt.Errorf("set %s: %w", path, err)
+		}
+	}
+	for path, replace := range r.Replace {
+		field, err := fieldByPath(pk, path)
+		if err != nil {
+			return err
+		}
+		field.SetString(replace.re.ReplaceAllString(field.String(), replace.With))
+	}
+	return nil
+}
+
+type rulesHandler struct {
+	log   *logrus.Entry
+	rules []*packetRule
+	// sends a delayed packet on to the handlers after this one
+	reinject func(s *proxy.Session, pk packet.Packet, toServer bool)
+}
+
+func writePacket(s *proxy.Session, pk packet.Packet, toServer bool) error {
+	if toServer {
+		if s.Server == nil {
+			return nil
+		}
+		return s.Server.WritePacket(pk)
+	}
+	return s.ClientWritePacket(pk)
+}
+
+func (h *rulesHandler) inject(s *proxy.Session, rule *packetRule, toServer bool) {
+	for _, inject := range rule.Inject {
+		pk, _ := pcap2.NewPacketByName(inject.Packet)
+		_ = json.Unmarshal(inject.data, pk)
+		injectToServer := toServer
+		if inject.Direction != "" {
+			injectToServer = inject.Direction == "server"
+		}
+		if err := writePacket(s, pk, injectToServer); err != nil {
+			h.log.WithError(err).Warnf("injecting %s", inject.Packet)
+		}
+	}
+}
+
+func (h *rulesHandler) packetCallback(s *proxy.Session, pk packet.Packet, toServer bool, timeReceived time.Time, preLogin bool) (packet.Packet, error) {
+	// packets before spawning are handled by gophertunnel and cant be changed here
+	if preLogin {
+		return pk, nil
+	}
+	for _, rule := range h.rules {
+		if !rule.matches(pk, toServer) {
+			continue
+		}
+		h.inject(s, rule, toServer)
+		if rule.Drop {
+			return nil, nil
+		}
+		if err := rule.modify(pk); err != nil {
+			h.log.WithError(err).Warnf("modifying %s", rule.Packet)
+		}
+		if rule.delay > 0 {
+			time.AfterFunc(rule.delay, func() {
+				h.reinject(s, pk, toServer)
+			})
+			return nil, nil
+		}
+	}
+	return pk, nil
+}
+
+// NewRulesHandler loads a rules file that drops, delays, modifies or injects packets
+func NewRulesHandler(filename string) (func() *proxy.Handler, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file rulesFile
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i, rule := range file.Rules {
+		if err := rule.prepare(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+
+	log := logrus.WithField("part", "Rules")
+	log.Infof("Loaded %d rules from %s", len(file.Rules), filename)
+	return func() *proxy.Handler {
+		h := &rulesHandler{log: log, rules: file.Rules}
+		handler := &proxy.Handler{
+			Name:           "Rules",
+			PacketCallback: h.packetCallback,
+		}
+		h.reinject = func(s *proxy.Session, pk packet.Packet, toServer bool) {
+			s.ReinjectPacket(handler, pk, toServer)
+		}
+		return handler
+	}, nil
+}
+
+func init() {
+	proxy.NewRulesHandler = NewRulesHandler
+}
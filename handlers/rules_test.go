@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/proxy"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+func testRulesHandler(t *testing.T, rules string) *rulesHandler {
+	t.Helper()
+	var file rulesFile
+	if err := yaml.Unmarshal([]byte(rules), &file); err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range file.Rules {
+		if err := rule.prepare(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &rulesHandler{log: logrus.WithField("part", "Rules"), rules: file.Rules}
+}
+
+func TestRulesDrop(t *testing.T) {
+	h := testRulesHandler(t, `
+rules:
+  - packet: Text
+    direction: server
+    match:
+      Message: "~^/secret"
+    drop: true
+`)
+	pk, _ := h.packetCallback(nil, &packet.Text{Message: "/secret thing"}, true, time.Now(), false)
+	if pk != nil {
+		t.Fatal("matching packet was not dropped")
+	}
+	pk, _ = h.packetCallback(nil, &packet.Text{Message: "/secret thing"}, false, time.Now(), false)
+	if pk == nil {
+		t.Fatal("packet going the other way was dropped")
+	}
+	pk, _ = h.packetCallback(nil, &packet.Text{Message: "hello"}, true, time.Now(), false)
+	if pk == nil {
+		t.Fatal("packet that doesnt match was dropped")
+	}
+}
+
+func TestRulesModify(t *testing.T) {
+	h := testRulesHandler(t, `
+rules:
+  - packet: Text
+    set:
+      SourceName: server
+    replace:
+      Message:
+        pattern: "b+"
+        with: "c"
+`)
+	pk, _ := h.packetCallback(nil, &packet.Text{Message: "abbba"}, false, time.Now(), false)
+	text, ok := pk.(*packet.Text)
+	if !ok {
+		t.Fatalf("got %T", pk)
+	}
+	if text.SourceName != "server" || text.Message != "aca" {
+		t.Fatalf("got source %q message %q", text.SourceName, text.Message)
+	}
+}
+
+func TestRulesDelay(t *testing.T) {
+	h := testRulesHandler(t, `
+rules:
+  - packet: Text
+    delay: 10ms
+    set:
+      Message: later
+`)
+	type reinjected struct {
+		pk       packet.Packet
+		toServer bool
+	}
+	sent := make(chan reinjected, 1)
+	h.reinject = func(s *proxy.Session, pk packet.Packet, toServer bool) {
+		sent <- reinjected{pk, toServer}
+	}
+
+	pk, _ := h.packetCallback(nil, &packet.Text{Message: "now"}, true, time.Now(), false)
+	if pk != nil {
+		t.Fatal("delayed packet was passed on right away")
+	}
+	select {
+	case r := <-sent:
+		text, ok := r.pk.(*packet.Text)
+		if !ok || text.Message != "later" || !r.toServer {
+			t.Fatalf("reinjected %#v to server %v", r.pk, r.toServer)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("delayed packet was never reinjected")
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/bedrock-tool/bedrocktool/handlers/worlds/entity"
-	"github.com/bedrock-tool/bedrocktool/utils"
 	"github.com/df-mc/dragonfly/server/world"
 	"github.com/dop251/goja"
 	"github.com/go-gl/mathgl/mgl32"
@@ -15,14 +14,13 @@ import (
 )
 
 func (v *VM) OnEntityAdd(entity *entity.Entity, isNew bool, timeReceived time.Time) (apply bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
 	if v.CB.OnEntityAdd == nil {
 		return true
 	}
-
-	v.lock.Lock()
-	defer v.lock.Unlock()
 	apply = true
-	err := utils.RecoverCall(func() error {
+	err := v.callback("EntityAdd", func() error {
 		applyV := v.CB.OnEntityAdd(
 			entity,
 			newEntityDataObject(v.runtime, entity.Metadata),
@@ -46,12 +44,12 @@ func (v *VM) OnEntityUpdate(
 	changedProperties map[string]any,
 	timeReceived time.Time,
 ) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
 	if v.CB.OnEntityUpdate == nil {
 		return
 	}
-	v.lock.Lock()
-	defer v.lock.Unlock()
-	err := utils.RecoverCall(func() error {
+	err := v.callback("EntityUpdate", func() error {
 		update := v.runtime.NewObject()
 		update.Set("Entity", entity)
 		update.Set("Metadata", newEntityDataObject(v.runtime, entity.Metadata))
@@ -78,14 +76,13 @@ func (v *VM) OnEntityPropertyChange(
 	previous any,
 	newValue any,
 ) bool {
-	if v.CB.OnEntityUpdate == nil {
-		return true
-	}
-
 	v.lock.Lock()
 	defer v.lock.Unlock()
+	if v.CB.OnEntityPropertyChange == nil {
+		return true
+	}
 	apply := true
-	err := utils.RecoverCall(func() error {
+	err := v.callback("EntityPropertyChange", func() error {
 		applyV := v.CB.OnEntityPropertyChange(entity, name, previous, newValue)
 		apply = goja.IsUndefined(applyV) || applyV.ToBoolean()
 		return nil
@@ -97,14 +94,13 @@ func (v *VM) OnEntityPropertyChange(
 }
 
 func (v *VM) OnChunkAdd(pos world.ChunkPos, timeReceived time.Time) (apply bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
 	if v.CB.OnChunkAdd == nil {
 		return true
 	}
-
-	v.lock.Lock()
-	defer v.lock.Unlock()
 	apply = true
-	err := utils.RecoverCall(func() error {
+	err := v.callback("ChunkAdd", func() error {
 		applyV := v.CB.OnChunkAdd(pos, float64(timeReceived.UnixMilli()))
 		apply = goja.IsUndefined(applyV) || applyV.ToBoolean()
 		return nil
@@ -117,13 +113,12 @@ func (v *VM) OnChunkAdd(pos world.ChunkPos, timeReceived time.Time) (apply bool)
 }
 
 func (v *VM) OnChunkData(pos world.ChunkPos) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
 	if v.CB.OnChunkData == nil {
 		return
 	}
-
-	v.lock.Lock()
-	defer v.lock.Unlock()
-	err := utils.RecoverCall(func() error {
+	err := v.callback("ChunkData", func() error {
 		v.CB.OnChunkData(pos)
 		return nil
 	})
@@ -133,14 +128,13 @@ func (v *VM) OnChunkData(pos world.ChunkPos) {
 }
 
 func (v *VM) OnBlockUpdate(name string, properties map[string]any, pos protocol.BlockPos, timeReceived time.Time) (apply bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
 	if v.CB.OnBlockUpdate == nil {
 		return true
 	}
-
-	v.lock.Lock()
-	defer v.lock.Unlock()
 	apply = true
-	err := utils.RecoverCall(func() error {
+	err := v.callback("BlockUpdate", func() error {
 		applyV := v.CB.OnBlockUpdate(name, properties, pos, float64(timeReceived.UnixMilli()))
 		apply = goja.IsUndefined(applyV) || applyV.ToBoolean()
 		return nil
@@ -154,11 +148,12 @@ func (v *VM) OnBlockUpdate(name string, properties map[string]any, pos protocol.
 }
 
 func (v *VM) OnSpawnParticle(name string, position mgl32.Vec3, timeReceived time.Time) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
 	if v.CB.OnSpawnParticle == nil {
 		return
 	}
-
-	err := utils.RecoverCall(func() error {
+	err := v.callback("SpawnParticle", func() error {
 		v.CB.OnSpawnParticle(name, position, float64(timeReceived.UnixMilli()))
 		return nil
 	})
@@ -168,13 +163,13 @@ func (v *VM) OnSpawnParticle(name string, position mgl32.Vec3, timeReceived time
 }
 
 func (v *VM) OnPacket(pk packet.Packet, toServer bool, timeReceived time.Time) (apply bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
 	if v.CB.OnPacket == nil {
 		return true
 	}
-
-	v.lock.Lock()
-	defer v.lock.Unlock()
-	err := utils.RecoverCall(func() error {
+	apply = true
+	err := v.callback("Packet", func() error {
 		packetName := strings.Split(reflect.TypeOf(pk).String(), ".")[1]
 		applyV := v.CB.OnPacket(packetName, pk, toServer, float64(timeReceived.UnixMilli()))
 		apply = goja.IsUndefined(applyV) || applyV.ToBoolean()
@@ -0,0 +1,159 @@
+package scripting
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/proxy"
+	"github.com/dop251/goja"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// vms of the running sessions, so other handlers can give the script more to work with
+var (
+	sessionVMs   = map[*proxy.Session]*VM{}
+	sessionVMsMu sync.Mutex
+)
+
+// ForSession returns the vm running the script in the session, nil if there is no script
+func ForSession(s *proxy.Session) *VM {
+	sessionVMsMu.Lock()
+	defer sessionVMsMu.Unlock()
+	return sessionVMs[s]
+}
+
+type scriptCommand struct {
+	name, description string
+	callback          goja.Callable
+}
+
+type scriptHandler struct {
+	path      string
+	vm        *VM
+	session   *proxy.Session
+	stopWatch func()
+}
+
+// NewScriptHandler loads a script that can watch and send packets in every session,
+// it is reloaded when its files change
+func NewScriptHandler(path string) (func() *proxy.Handler, error) {
+	// syntax errors before connecting
+	if err := CheckScript(path); err != nil {
+		return nil, err
+	}
+
+	var previous *scriptHandler
+	return func() *proxy.Handler {
+		// after a reconnect the script keeps running
+		h := previous
+		if h == nil || !h.session.Reconnecting() {
+			h = &scriptHandler{path: path}
+		}
+		previous = h
+
+		return &proxy.Handler{
+			Name:           "Script",
+			SessionStart:   h.onSessionStart,
+			PacketCallback: h.packetCallback,
+			OnSessionEnd: func(s *proxy.Session, _ *sync.WaitGroup) {
+				sessionVMsMu.Lock()
+				delete(sessionVMs, s)
+				sessionVMsMu.Unlock()
+				if !s.Reconnecting() && h.stopWatch != nil {
+					h.stopWatch()
+				}
+			},
+		}
+	}, nil
+}
+
+func (h *scriptHandler) onSessionStart(s *proxy.Session, _ string) error {
+	h.session = s
+	if h.vm == nil {
+		h.vm = New()
+		h.vm.DisplayChatMessage = func(msg string) {
+			h.session.SendMessage(msg)
+		}
+		h.vm.SendToServer = func(pk packet.Packet) error {
+			if h.session.Server == nil {
+				return fmt.Errorf("not connected to a server")
+			}
+			return h.session.Server.WritePacket(pk)
+		}
+		h.vm.SendToClient = func(pk packet.Packet) error {
+			return h.session.ClientWritePacket(pk)
+		}
+		h.vm.AddCommand = h.addCommand
+		// a script that fails or runs out of time is started again when it changes
+		if err := h.vm.Load(h.path); err != nil {
+			h.vm.log.Errorf("loading script: %s", err)
+		}
+		h.stopWatch = h.vm.Watch(1 * time.Second)
+	} else {
+		// commands are per session
+		for _, cmd := range h.vm.commands {
+			h.addCommand(cmd)
+		}
+	}
+
+	sessionVMsMu.Lock()
+	sessionVMs[s] = h.vm
+	sessionVMsMu.Unlock()
+	return nil
+}
+
+func (h *scriptHandler) addCommand(cmd scriptCommand) {
+	h.session.AddCommand(func(args []string) bool {
+		return h.vm.runCommand(cmd.name, args)
+	}, protocol.Command{
+		Name:        cmd.name,
+		Description: cmd.description,
+	})
+}
+
+func (h *scriptHandler) packetCallback(_ *proxy.Session, pk packet.Packet, toServer bool, timeReceived time.Time, _ bool) (packet.Packet, error) {
+	h.vm.Tick(timeReceived)
+	if !h.vm.OnPacket(pk, toServer, timeReceived) {
+		return nil, nil
+	}
+	return pk, nil
+}
+
+// runCommand runs the command the current script registered with the name, it might have been reloaded since
+func (v *VM) runCommand(name string, args []string) (ok bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	i := slices.IndexFunc(v.commands, func(cmd scriptCommand) bool {
+		return cmd.name == name
+	})
+	if i == -1 {
+		v.log.Warnf("the script has no command %s anymore", name)
+		return false
+	}
+	if v.stopped != nil {
+		v.DisplayChatMessage("The script is stopped until it changes")
+		return true
+	}
+	cmd := v.commands[i]
+	ok = true
+	err := v.callback("the command "+name, func() error {
+		okV, err := cmd.callback(goja.Undefined(), v.runtime.ToValue(args))
+		if err != nil {
+			return err
+		}
+		ok = goja.IsUndefined(okV) || okV.ToBoolean()
+		return nil
+	})
+	if err != nil {
+		v.log.Error(err)
+		return false
+	}
+	return ok
+}
+
+func init() {
+	proxy.NewScriptHandler = NewScriptHandler
+}
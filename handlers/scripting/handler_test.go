@@ -0,0 +1,84 @@
+package scripting
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func TestScriptHandlerPacket(t *testing.T) {
+	v := loadTestScript(t, `
+		globalThis.log = [];
+		events.register("Packet", (name, pk, toServer) => {
+			log.push(name);
+			return name !== "Text";
+		});
+		setTimeout(() => log.push("timer"), 0);
+	`)
+	h := &scriptHandler{vm: v}
+
+	now := time.UnixMilli(1700000000000)
+	if pk, _ := h.packetCallback(nil, &packet.Text{}, false, now, false); pk != nil {
+		t.Fatal("the script dropped the packet but it was passed on")
+	}
+	if pk, _ := h.packetCallback(nil, &packet.SetTime{}, false, now, false); pk == nil {
+		t.Fatal("the packet was dropped")
+	}
+	// timers run before the packet that made them due
+	if log := scriptLog(t, v); !slices.Equal(log, []string{"timer", "Text", "SetTime"}) {
+		t.Fatalf("unexpected order %v", log)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.js")
+	write := func(source string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	version := func(v *VM) int64 {
+		return v.runtime.Get("version").ToInteger()
+	}
+
+	modTime := time.Now().Add(-time.Hour)
+	write("globalThis.version = 1", modTime)
+	v := New()
+	if err := v.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if v.changed() {
+		t.Fatal("nothing changed yet")
+	}
+
+	write("globalThis.version = 2", modTime.Add(time.Minute))
+	if !v.changed() {
+		t.Fatal("the change wasnt noticed")
+	}
+	if err := v.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if version(v) != 2 {
+		t.Fatalf("expected version 2, got %d", version(v))
+	}
+
+	// a broken change keeps the script that ran before, and isnt tried again until the next change
+	write("globalThis.version = ;", modTime.Add(2*time.Minute))
+	if err := v.Reload(); err == nil {
+		t.Fatal("reloading a broken script didnt fail")
+	}
+	if version(v) != 2 {
+		t.Fatalf("expected the old script to keep running, got version %d", version(v))
+	}
+	if v.changed() {
+		t.Fatal("the broken script would be reloaded again")
+	}
+}
@@ -0,0 +1,171 @@
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja_nodejs/require"
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// findSource finds the file for a path require asked for,
+// typescript imports leave out the extension or use .js for .ts files
+func findSource(path string) (string, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return "", require.ModuleFileDoesNotExistError
+		}
+		return path, nil
+	}
+	var candidates []string
+	switch filepath.Ext(path) {
+	case ".js":
+		candidates = append(candidates, strings.TrimSuffix(path, ".js")+".ts")
+	case "":
+		candidates = append(candidates, path+".ts")
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", require.ModuleFileDoesNotExistError
+}
+
+// transpile turns typescript and es modules into commonjs that goja can run
+func transpile(path string, data []byte) ([]byte, error) {
+	loader := api.LoaderJS
+	switch filepath.Ext(path) {
+	case ".json":
+		return data, nil
+	case ".ts":
+		loader = api.LoaderTS
+	}
+	result := api.Transform(string(data), api.TransformOptions{
+		Loader:     loader,
+		Format:     api.FormatCommonJS,
+		Target:     api.ES2017,
+		Sourcefile: path,
+		Sourcemap:  api.SourceMapInline,
+	})
+	if len(result.Errors) > 0 {
+		var errs []error
+		for _, msg := range result.Errors {
+			if msg.Location != nil {
+				errs = append(errs, fmt.Errorf("%s:%d:%d: %s", msg.Location.File, msg.Location.Line, msg.Location.Column, msg.Text))
+			} else {
+				errs = append(errs, errors.New(msg.Text))
+			}
+		}
+		return nil, errors.Join(errs...)
+	}
+	return result.Code, nil
+}
+
+// CheckScript finds syntax errors in a script file and its permissions before it is run
+func CheckScript(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if _, err := loadPermissions(path); err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = transpile(path, data)
+	return err
+}
+
+// scriptFolder is the folder the script may load modules from
+func (v *VM) scriptFolder() string {
+	if info, err := os.Stat(v.path); err == nil && info.IsDir() {
+		return v.path
+	}
+	return filepath.Dir(v.path)
+}
+
+// loadSource is the source loader for require, it remembers the files so they can be watched.
+// only files in the folder of the script can be loaded, so a shared script cant read the rest of the disk
+func (v *VM) loadSource(path string) ([]byte, error) {
+	path, err := findSource(path)
+	if err != nil {
+		return nil, err
+	}
+	folder := v.scriptFolder()
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+		if resolvedFolder, err := filepath.EvalSymlinks(folder); err == nil {
+			folder = resolvedFolder
+		}
+	}
+	rel, err := filepath.Rel(folder, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("the script can only load files in %s, not %s", folder, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	v.files[path] = info.ModTime()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return transpile(path, data)
+}
+
+// changed returns if a file of the script changed since it was loaded
+func (v *VM) changed() bool {
+	v.lock.Lock()
+	files := maps.Clone(v.files)
+	v.lock.Unlock()
+	for path, modTime := range files {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch reloads the script when one of its files changes, until stop is called
+func (v *VM) Watch(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			if !v.changed() {
+				continue
+			}
+			if err := v.Reload(); err != nil {
+				v.log.Errorf("reloading script: %s", err)
+				continue
+			}
+			v.log.Info("Reloaded script")
+			if v.DisplayChatMessage != nil {
+				v.DisplayChatMessage("Reloaded script")
+			}
+		}
+	}()
+	return sync.OnceFunc(func() {
+		close(done)
+	})
+}
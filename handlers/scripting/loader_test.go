@@ -0,0 +1,115 @@
+package scripting
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dop251/goja_nodejs/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.ts": "", "b.js": "", "sub/c.ts": ""})
+
+	type test struct {
+		path, expected string
+	}
+	var tests = []test{
+		{path: "a", expected: "a.ts"},
+		// typescript imports name the file they compile to
+		{path: "a.js", expected: "a.ts"},
+		{path: "b.js", expected: "b.js"},
+		{path: "b"},
+		{path: "sub"},
+		{path: "missing.js"},
+	}
+	for _, tt := range tests {
+		got, err := findSource(filepath.Join(dir, tt.path))
+		if tt.expected == "" {
+			if !errors.Is(err, require.ModuleFileDoesNotExistError) {
+				t.Errorf("%s: expected the module to not exist, got %q %v", tt.path, got, err)
+			}
+			continue
+		}
+		if err != nil || got != filepath.Join(dir, tt.expected) {
+			t.Errorf("%s: expected %s, got %q %v", tt.path, tt.expected, got, err)
+		}
+	}
+}
+
+func TestLoadTypescriptModules(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"index.ts": `
+			import { double } from "./lib/math.js";
+			globalThis.result = double(21);
+		`,
+		"lib/math.ts": `export function double(n: number): number { return n * 2 }`,
+	})
+	v := New()
+	if err := v.Load(filepath.Join(dir, "index.ts")); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.runtime.Get("result").ToInteger(); got != 42 {
+		t.Fatalf("expected 42, got %d", got)
+	}
+	if len(v.files) != 2 {
+		t.Fatalf("expected both files to be watched, got %v", v.files)
+	}
+}
+
+func TestLoadSourceConfined(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"secret.js":        `module.exports = "secret"`,
+		"script/index.js":  `globalThis.loaded = true`,
+		"script/escape.js": `require("../secret.js")`,
+	})
+	if err := os.Symlink(filepath.Join(root, "secret.js"), filepath.Join(root, "script", "link.js")); err != nil {
+		t.Fatal(err)
+	}
+	v := New()
+	if err := v.Load(filepath.Join(root, "script")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"../secret.js", "link.js"} {
+		if _, err := v.loadSource(filepath.Join(root, "script", name)); err == nil {
+			t.Errorf("%s could be loaded from outside the script folder", name)
+		}
+	}
+	if _, err := v.require.Require(filepath.Join(root, "script", "escape.js")); err == nil {
+		t.Error("the script could require a file outside of its folder")
+	}
+	if _, err := v.loadSource(filepath.Join(root, "script", "index.js")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckScript(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"good.ts": `const a: number = 1`,
+		"bad.ts":  `const a: = 1`,
+	})
+	if err := CheckScript(filepath.Join(dir, "good.ts")); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckScript(filepath.Join(dir, "bad.ts")); err == nil {
+		t.Fatal("syntax error wasnt found")
+	}
+}
@@ -0,0 +1,148 @@
+package scripting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils"
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultTimeBudget is how long a callback may run when the manifest doesnt say
+const defaultTimeBudget = 100 * time.Millisecond
+
+// longTimeBudget is for running the script the first time and before saving, which are not on the packet path
+const longTimeBudget = 5 * time.Second
+
+// Permissions is what a script may do, read from a manifest next to it.
+// without one it can only watch packets and edit the world.
+// modules are only loaded from the folder of the script
+type Permissions struct {
+	// folders in the data folder the script may create files in, "." for all of it
+	FileSystem []string `json:"fileSystem"`
+	// names of the packets the script may send, "*" for all
+	SendPackets []string `json:"sendPackets"`
+	// how long one callback may run in milliseconds before the script is stopped
+	TimeBudget int `json:"timeBudget"`
+	// hosts the script may connect to. there are no bindings that open connections yet,
+	// so nothing is allowed whatever is listed
+	Network []string `json:"network"`
+
+	manifest string
+}
+
+// permissionsPath is foo.permissions.json for foo.js or foo.ts, permissions.json in a script folder
+func permissionsPath(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, "permissions.json")
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".permissions.json"
+}
+
+// loadPermissions reads the manifest of the script at path, it is optional
+func loadPermissions(path string) (p Permissions, err error) {
+	p.manifest = permissionsPath(path)
+	data, err := os.ReadFile(p.manifest)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err = json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("%s: %w", p.manifest, err)
+	}
+	if p.TimeBudget < 0 {
+		return p, fmt.Errorf("%s: timeBudget can't be negative", p.manifest)
+	}
+	if len(p.Network) > 0 {
+		logrus.Warnf("%s: scripts can't open connections yet, network is ignored", p.manifest)
+	}
+	return p, nil
+}
+
+func (p *Permissions) timeBudget() time.Duration {
+	if p.TimeBudget == 0 {
+		return defaultTimeBudget
+	}
+	return time.Duration(p.TimeBudget) * time.Millisecond
+}
+
+// filePath returns where a file the script names is, if it is in a folder it may write to
+func (p *Permissions) filePath(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return "", fmt.Errorf("'%s' has to be relative to the data folder", name)
+	}
+	path := filepath.Clean(utils.PathData(name))
+	for _, scope := range p.FileSystem {
+		rel, err := filepath.Rel(filepath.Clean(utils.PathData(scope)), path)
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("the script may not write '%s', allow it in fileSystem of %s", name, p.manifest)
+}
+
+func (p *Permissions) canSend(name string) error {
+	if slices.Contains(p.SendPackets, "*") || slices.Contains(p.SendPackets, name) {
+		return nil
+	}
+	return fmt.Errorf("the script may not send %s, allow it in sendPackets of %s", name, p.manifest)
+}
+
+// callback runs fn with the time budget of the script
+func (v *VM) callback(what string, fn func() error) error {
+	return v.call(what, v.permissions.timeBudget(), fn)
+}
+
+// call runs a callback of the script with the lock held, it is interrupted when it takes longer than the budget.
+// a script that was interrupted is stopped until it is reloaded
+func (v *VM) call(what string, budget time.Duration, fn func() error) error {
+	if v.stopped != nil {
+		return nil
+	}
+
+	var mu sync.Mutex
+	running := true
+	t := time.AfterFunc(budget, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if running {
+			v.runtime.Interrupt(fmt.Errorf("%s ran longer than %s", what, budget))
+		}
+	})
+	err := utils.RecoverCall(fn)
+	mu.Lock()
+	running = false
+	mu.Unlock()
+	t.Stop()
+	v.runtime.ClearInterrupt()
+
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		v.stop(interrupted)
+	}
+	return err
+}
+
+// stop drops everything the script registered and tells the user
+func (v *VM) stop(interrupted *goja.InterruptedError) {
+	v.stopped = interrupted
+	v.CB = callbacks{}
+	clear(v.timers)
+
+	// the error has where the script was, the chat only gets what happened
+	v.log.Errorf("Stopped the script, %s", interrupted)
+	msg := fmt.Sprintf("Stopped the script, %s", interrupted.Value())
+	if v.DisplayChatMessage != nil {
+		v.DisplayChatMessage(msg + ", it is started again when it changes")
+	}
+}
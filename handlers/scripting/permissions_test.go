@@ -0,0 +1,107 @@
+package scripting
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func TestLoadPermissions(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.js")
+
+	p, err := loadPermissions(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.manifest != filepath.Join(dir, "script.permissions.json") || p.timeBudget() != defaultTimeBudget {
+		t.Fatalf("unexpected defaults %+v", p)
+	}
+
+	for _, manifest := range []string{`{"timeBudget": -1}`, `{"fileSystem": "logs"}`} {
+		writeFiles(t, dir, map[string]string{"script.permissions.json": manifest})
+		if _, err := loadPermissions(script); err == nil {
+			t.Errorf("manifest %s was accepted", manifest)
+		}
+	}
+
+	writeFiles(t, dir, map[string]string{"script.permissions.json": `{"timeBudget": 250, "sendPackets": ["Text"]}`})
+	p, err = loadPermissions(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.timeBudget() != 250*time.Millisecond {
+		t.Fatalf("expected 250ms, got %s", p.timeBudget())
+	}
+	if p.canSend("Text") != nil || p.canSend("Transfer") == nil {
+		t.Fatal("only Text may be sent")
+	}
+	if (&Permissions{SendPackets: []string{"*"}}).canSend("Transfer") != nil {
+		t.Fatal("* should allow every packet")
+	}
+}
+
+func TestPermissionsFilePath(t *testing.T) {
+	p := Permissions{FileSystem: []string{"logs"}}
+	if path, err := p.filePath("logs/today.txt"); err != nil || path != filepath.Clean(utils.PathData("logs/today.txt")) {
+		t.Fatalf("expected a path in logs, got %q %v", path, err)
+	}
+	for _, name := range []string{"logs", "other.txt", "logs/../other.txt", "../outside.txt", "logsmore/a.txt", filepath.Join(t.TempDir(), "abs.txt")} {
+		if path, err := p.filePath(name); err == nil {
+			t.Errorf("%s is allowed as %s", name, path)
+		}
+	}
+
+	p = Permissions{FileSystem: []string{"."}}
+	if _, err := p.filePath("anything.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.filePath("../outside.txt"); err == nil {
+		t.Fatal("the data folder was left")
+	}
+}
+
+func TestCallInterrupt(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"script.js":               `events.register("Packet", () => { for (;;) {} })`,
+		"script.permissions.json": `{"timeBudget": 50}`,
+	})
+	v := New()
+	var messages []string
+	v.DisplayChatMessage = func(msg string) {
+		messages = append(messages, msg)
+	}
+	if err := v.Load(filepath.Join(dir, "script.js")); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan bool, 1)
+	go func() { done <- v.OnPacket(&packet.Text{}, false, time.Now()) }()
+	select {
+	case apply := <-done:
+		if !apply {
+			t.Fatal("the packet of an interrupted callback should be kept")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the callback wasnt interrupted")
+	}
+	if v.stopped == nil || v.CB.OnPacket != nil {
+		t.Fatal("the script wasnt stopped")
+	}
+	if len(messages) != 1 || !strings.HasPrefix(messages[0], "Stopped the script") {
+		t.Fatalf("the user wasnt told, got %v", messages)
+	}
+
+	// nothing runs until the script changes
+	if err := v.call("test", time.Second, func() error {
+		t.Fatal("a stopped script ran")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,13 +1,15 @@
 package scripting
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/bedrock-tool/bedrocktool/handlers/worlds/entity"
 	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
-	"github.com/bedrock-tool/bedrocktool/utils"
 	"github.com/df-mc/dragonfly/server/world"
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/console"
@@ -20,27 +22,53 @@ import (
 	_ "embed"
 )
 
+type callbacks struct {
+	OnEntityAdd            func(entity *entity.Entity, metadata *goja.Object, timeReceived float64, isNew bool) (apply goja.Value)
+	OnChunkData            func(pos world.ChunkPos)
+	OnChunkAdd             func(pos world.ChunkPos, timeReceived float64) (apply goja.Value)
+	OnEntityUpdate         func(update *goja.Object, timeReceived float64)
+	OnEntityPropertyChange func(entity *entity.Entity, name string, previous any, newValue any) (apply goja.Value)
+	OnBlockUpdate          func(name string, properties map[string]any, pos protocol.BlockPos, timeReceived float64) (apply goja.Value)
+	OnSpawnParticle        func(name string, pos mgl32.Vec3, timeReceived float64)
+	OnPacket               func(name string, pk packet.Packet, toServer bool, timeReceived float64) (apply goja.Value)
+	OnBeforeSave           func(world *goja.Object, name string)
+}
+
 type VM struct {
 	runtime *goja.Runtime
+	require *require.RequireModule
 	lock    sync.Mutex
 	log     *logrus.Entry
 
-	CB struct {
-		OnEntityAdd            func(entity *entity.Entity, metadata *goja.Object, timeReceived float64, isNew bool) (apply goja.Value)
-		OnChunkData            func(pos world.ChunkPos)
-		OnChunkAdd             func(pos world.ChunkPos, timeReceived float64) (apply goja.Value)
-		OnEntityUpdate         func(update *goja.Object, timeReceived float64)
-		OnEntityPropertyChange func(entity *entity.Entity, name string, previous any, newValue any) (apply goja.Value)
-		OnBlockUpdate          func(name string, properties map[string]any, pos protocol.BlockPos, timeReceived float64) (apply goja.Value)
-		OnSpawnParticle        func(name string, pos mgl32.Vec3, timeReceived float64)
-		OnPacket               func(name string, pk packet.Packet, toServer bool, timeReceived float64) (apply goja.Value)
-	}
+	// entry point of the script and the files it loaded with their modification time
+	path  string
+	files map[string]time.Time
+
+	CB callbacks
+
+	// only set while the worlds handler is running
+	GetWorld     func() *worldstate.World
+	SetIngameMap func(enabled bool)
 
-	GetWorld           func() *worldstate.World
 	DisplayChatMessage func(msg string)
-	SetIngameMap       func(enabled bool)
+	SendToServer       func(pk packet.Packet) error
+	SendToClient       func(pk packet.Packet) error
+	AddCommand         func(cmd scriptCommand)
+
+	commands []scriptCommand
+
+	permissions Permissions
+	// set when a callback ran out of time, nothing of the script runs until it is reloaded
+	stopped *goja.InterruptedError
+
+	// session time of the last packet, timers run on it
+	clock       time.Time
+	timers      map[int64]*timer
+	nextTimerID int64
 }
 
+var errNoWorld = errors.New("needs the worlds handler")
+
 type LogrusPrinter struct{}
 
 func (p LogrusPrinter) Log(s string) {
@@ -61,12 +89,22 @@ func init() {
 
 func New() *VM {
 	v := &VM{
-		runtime: goja.New(),
-		log:     logrus.WithField("part", "jsvm"),
+		log: logrus.WithField("part", "jsvm"),
 	}
+	v.setup()
+	return v
+}
+
+// setup makes a new runtime with all bindings, forgetting everything a previous script registered
+func (v *VM) setup() {
+	v.runtime = goja.New()
+	v.CB = callbacks{}
+	v.commands = nil
+	v.stopped = nil
+	v.files = make(map[string]time.Time)
 
-	registry := new(require.Registry)
-	registry.Enable(v.runtime)
+	registry := require.NewRegistry(require.WithLoader(v.loadSource))
+	v.require = registry.Enable(v.runtime)
 	console.Enable(v.runtime)
 
 	events := v.runtime.NewObject()
@@ -88,6 +126,8 @@ func New() *VM {
 			err = v.runtime.ExportTo(callback, &v.CB.OnSpawnParticle)
 		case "Packet":
 			err = v.runtime.ExportTo(callback, &v.CB.OnPacket)
+		case "BeforeSave":
+			err = v.runtime.ExportTo(callback, &v.CB.OnBeforeSave)
 		}
 		return err
 	})
@@ -96,7 +136,11 @@ func New() *VM {
 	fs := v.runtime.NewObject()
 	fs.Set("create", func(call goja.FunctionCall) goja.Value {
 		name := call.Argument(0).String()
-		file, err := os.Create(utils.PathData(name))
+		path, err := v.permissions.filePath(name)
+		if err != nil {
+			return v.runtime.ToValue(err)
+		}
+		file, err := os.Create(path)
 		if err != nil {
 			return v.runtime.ToValue(fmt.Errorf("failed to create file '%s': %w", name, err))
 		}
@@ -130,6 +174,9 @@ func New() *VM {
 	})
 
 	v.runtime.Set("setIngameMap", func(call goja.FunctionCall) goja.Value {
+		if v.SetIngameMap == nil {
+			panic(v.runtime.NewGoError(errNoWorld))
+		}
 		enabled := call.Argument(0).ToBoolean()
 		v.SetIngameMap(enabled)
 		return goja.Undefined()
@@ -140,6 +187,9 @@ func New() *VM {
 		x := call.Argument(0).ToInteger()
 		z := call.Argument(1).ToInteger()
 
+		if v.GetWorld == nil {
+			panic(v.runtime.NewGoError(errNoWorld))
+		}
 		obj := v.runtime.NewObject()
 
 		w := v.GetWorld()
@@ -195,12 +245,67 @@ func New() *VM {
 	})
 	v.runtime.GlobalObject().Set("chunks", chunks)
 
-	return v
+	v.runtime.GlobalObject().Set("world", v.newWorldObject(func() *worldstate.World {
+		if v.GetWorld == nil {
+			return nil
+		}
+		return v.GetWorld()
+	}))
+
+	v.registerSession()
+	v.registerTimers()
 }
 
-func (v *VM) Load(script string) error {
-	_, err := v.runtime.RunScript("script.js", script)
+// Load runs the script at path, a .js or .ts file or a folder with an index or package.json,
+// what it imports is loaded from disk
+func (v *VM) Load(path string) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	v.path = path
+	if err = v.loadPermissions(); err != nil {
+		return err
+	}
+	return v.call("loading the script", longTimeBudget, func() error {
+		_, err := v.require.Require(v.path)
+		return err
+	})
+}
+
+// loadPermissions reads the manifest of the script, it is watched like the files of the script
+func (v *VM) loadPermissions() error {
+	permissions, err := loadPermissions(v.path)
+	if err != nil {
+		return err
+	}
+	v.permissions = permissions
+	if info, err := os.Stat(permissions.manifest); err == nil {
+		v.files[permissions.manifest] = info.ModTime()
+	}
+	return nil
+}
+
+// Reload runs the script again in a new runtime, the old one is kept if it fails
+func (v *VM) Reload() error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	runtime, requireModule, cb, commands, timers := v.runtime, v.require, v.CB, v.commands, v.timers
+	permissions, stopped := v.permissions, v.stopped
+	v.setup()
+	err := v.loadPermissions()
+	if err == nil {
+		err = v.call("loading the script", longTimeBudget, func() error {
+			_, err := v.require.Require(v.path)
+			return err
+		})
+	}
 	if err != nil {
+		// files stay the new ones so it is only tried again after the next change
+		v.runtime, v.require, v.CB, v.commands, v.timers = runtime, requireModule, cb, commands, timers
+		v.permissions, v.stopped = permissions, stopped
 		return err
 	}
 	return nil
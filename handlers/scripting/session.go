@@ -0,0 +1,82 @@
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/dop251/goja"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// packets by the name of their type, the same names the Packet event gets
+var (
+	clientPackets = packetsByName(packet.NewClientPool())
+	serverPackets = packetsByName(packet.NewServerPool())
+)
+
+func packetsByName(pool packet.Pool) map[string]func() packet.Packet {
+	out := make(map[string]func() packet.Packet, len(pool))
+	for _, fn := range pool {
+		out[reflect.TypeOf(fn()).Elem().Name()] = fn
+	}
+	return out
+}
+
+// newPacket makes a packet from its name and an object with the fields to set
+func (v *VM) newPacket(pool map[string]func() packet.Packet, name string, fields goja.Value) (packet.Packet, error) {
+	fn, ok := pool[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown packet %s", name)
+	}
+	pk := fn()
+	if fields != nil && !goja.IsUndefined(fields) && !goja.IsNull(fields) {
+		if err := v.runtime.ExportTo(fields, pk); err != nil {
+			return nil, fmt.Errorf("packet %s: %w", name, err)
+		}
+	}
+	return pk, nil
+}
+
+func (v *VM) registerSession() {
+	session := v.runtime.NewObject()
+	session.Set("sendToServer", func(name string, fields goja.Value) error {
+		if v.SendToServer == nil {
+			return errors.New("not connected to a server")
+		}
+		if err := v.permissions.canSend(name); err != nil {
+			return err
+		}
+		pk, err := v.newPacket(clientPackets, name, fields)
+		if err != nil {
+			return err
+		}
+		return v.SendToServer(pk)
+	})
+	session.Set("sendToClient", func(name string, fields goja.Value) error {
+		if v.SendToClient == nil {
+			return errors.New("no client connected")
+		}
+		if err := v.permissions.canSend(name); err != nil {
+			return err
+		}
+		pk, err := v.newPacket(serverPackets, name, fields)
+		if err != nil {
+			return err
+		}
+		return v.SendToClient(pk)
+	})
+	session.Set("addCommand", func(name, description string, callback goja.Value) error {
+		fn, ok := goja.AssertFunction(callback)
+		if !ok {
+			return errors.New("callback is not a function")
+		}
+		cmd := scriptCommand{name: name, description: description, callback: fn}
+		v.commands = append(v.commands, cmd)
+		if v.AddCommand != nil {
+			v.AddCommand(cmd)
+		}
+		return nil
+	})
+	v.runtime.GlobalObject().Set("session", session)
+}
@@ -0,0 +1,105 @@
+package scripting
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+type timer struct {
+	id       int64
+	at       time.Time
+	interval time.Duration // 0 for timeouts
+	fn       goja.Callable
+	args     []goja.Value
+}
+
+func (v *VM) registerTimers() {
+	v.timers = make(map[int64]*timer)
+	v.runtime.Set("setTimeout", func(call goja.FunctionCall) goja.Value {
+		return v.addTimer(call, false)
+	})
+	v.runtime.Set("setInterval", func(call goja.FunctionCall) goja.Value {
+		return v.addTimer(call, true)
+	})
+	clearTimer := func(id int64) {
+		delete(v.timers, id)
+	}
+	v.runtime.Set("clearTimeout", clearTimer)
+	v.runtime.Set("clearInterval", clearTimer)
+}
+
+// addTimer is called by the script so the lock is already held
+func (v *VM) addTimer(call goja.FunctionCall, repeat bool) goja.Value {
+	fn, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(v.runtime.NewTypeError("callback is not a function"))
+	}
+	delay := max(time.Duration(call.Argument(1).ToInteger())*time.Millisecond, 0)
+	var args []goja.Value
+	if len(call.Arguments) > 2 {
+		args = slices.Clone(call.Arguments[2:])
+	}
+
+	v.nextTimerID++
+	t := &timer{
+		id:   v.nextTimerID,
+		at:   v.clock.Add(delay),
+		fn:   fn,
+		args: args,
+	}
+	if repeat {
+		t.interval = max(delay, time.Millisecond)
+	}
+	v.timers[t.id] = t
+	return v.runtime.ToValue(t.id)
+}
+
+// Tick runs the timers that are due at now, which is the time of the packet being handled
+func (v *VM) Tick(now time.Time) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.clock.IsZero() {
+		// timers set before the first packet start from it
+		for _, t := range v.timers {
+			t.at = now.Add(t.at.Sub(time.Time{}))
+		}
+	}
+	if now.After(v.clock) {
+		v.clock = now
+	}
+	if len(v.timers) == 0 {
+		return
+	}
+
+	var due []*timer
+	for _, t := range v.timers {
+		if !t.at.After(v.clock) {
+			due = append(due, t)
+		}
+	}
+	slices.SortFunc(due, func(a, b *timer) int {
+		return cmp.Or(a.at.Compare(b.at), cmp.Compare(a.id, b.id))
+	})
+
+	for _, t := range due {
+		// cleared by an earlier one
+		if _, ok := v.timers[t.id]; !ok {
+			continue
+		}
+		if t.interval > 0 {
+			t.at = v.clock.Add(t.interval)
+		} else {
+			delete(v.timers, t.id)
+		}
+		err := v.callback("a timer", func() error {
+			_, err := t.fn(goja.Undefined(), t.args...)
+			return err
+		})
+		if err != nil {
+			v.log.Error(err)
+		}
+	}
+}
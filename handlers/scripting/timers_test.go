@@ -0,0 +1,67 @@
+package scripting
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// loadTestScript writes a script to a new folder and runs it
+func loadTestScript(t *testing.T, source string) *VM {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.js")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v := New()
+	if err := v.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// scriptLog returns the strings the script pushed to its log array
+func scriptLog(t *testing.T, v *VM) []string {
+	t.Helper()
+	var log []string
+	if err := v.runtime.ExportTo(v.runtime.Get("log"), &log); err != nil {
+		t.Fatal(err)
+	}
+	return log
+}
+
+func TestTimers(t *testing.T) {
+	v := loadTestScript(t, `
+		globalThis.log = [];
+		setTimeout((what) => log.push(what), 100, "timeout");
+		const id = setInterval(() => {
+			log.push("interval");
+			if (log.length > 3) clearInterval(id);
+		}, 50);
+		const cleared = setTimeout(() => log.push("cleared"), 10);
+		clearTimeout(cleared);
+	`)
+
+	// timers set before the first packet start from it
+	start := time.UnixMilli(1700000000000)
+	v.Tick(start)
+	if log := scriptLog(t, v); len(log) != 0 {
+		t.Fatalf("nothing should be due yet, got %v", log)
+	}
+
+	v.Tick(start.Add(50 * time.Millisecond))
+	// a packet with an older time doesnt move the clock back
+	v.Tick(start.Add(20 * time.Millisecond))
+	v.Tick(start.Add(100 * time.Millisecond))
+	if log := scriptLog(t, v); !slices.Equal(log, []string{"interval", "timeout", "interval"}) {
+		t.Fatalf("unexpected order %v", log)
+	}
+
+	v.Tick(start.Add(time.Second))
+	v.Tick(start.Add(2 * time.Second))
+	if log := scriptLog(t, v); len(log) != 4 || len(v.timers) != 0 {
+		t.Fatalf("interval wasnt cleared, log %v, %d timers left", log, len(v.timers))
+	}
+}
@@ -0,0 +1,207 @@
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/entity"
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/dop251/goja"
+)
+
+// blockProperties converts a js block state to the types block states use
+func blockProperties(value goja.Value) (map[string]any, error) {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil, nil
+	}
+	exported, ok := value.Export().(map[string]any)
+	if !ok {
+		return nil, errors.New("block state must be an object")
+	}
+	properties := make(map[string]any, len(exported))
+	for k, v := range exported {
+		switch v := v.(type) {
+		case string:
+			properties[k] = v
+		case bool:
+			if v {
+				properties[k] = uint8(1)
+			} else {
+				properties[k] = uint8(0)
+			}
+		case int64:
+			properties[k] = int32(v)
+		case float64:
+			properties[k] = int32(math.Round(v))
+		case uint8, int32:
+			properties[k] = v
+		default:
+			return nil, fmt.Errorf("invalid type %T for block state %s", v, k)
+		}
+	}
+	return properties, nil
+}
+
+func (v *VM) position(value goja.Value) cube.Pos {
+	var pos []int
+	if err := v.runtime.ExportTo(value, &pos); err != nil || len(pos) != 3 {
+		panic(v.runtime.NewTypeError("position must be [x, y, z]"))
+	}
+	return cube.Pos{pos[0], pos[1], pos[2]}
+}
+
+// newWorldObject makes the js api to query and edit the world get returns
+func (v *VM) newWorldObject(get func() *worldstate.World) *goja.Object {
+	world := func() *worldstate.World {
+		w := get()
+		if w == nil {
+			panic(v.runtime.NewGoError(errNoWorld))
+		}
+		return w
+	}
+	throw := func(err error) {
+		if err != nil {
+			panic(v.runtime.NewGoError(err))
+		}
+	}
+	blockPos := func(call goja.FunctionCall) cube.Pos {
+		return cube.Pos{
+			int(call.Argument(0).ToInteger()),
+			int(call.Argument(1).ToInteger()),
+			int(call.Argument(2).ToInteger()),
+		}
+	}
+	// checked before converting so 256 doesnt become layer 0
+	blockLayer := func(value goja.Value) uint8 {
+		layer := value.ToInteger()
+		if layer < 0 || layer > 1 {
+			panic(v.runtime.NewGoError(fmt.Errorf("layer %d, blocks only have layer 0 and 1", layer)))
+		}
+		return uint8(layer)
+	}
+
+	obj := v.runtime.NewObject()
+
+	// getBlock(x, y, z, layer?)
+	obj.Set("getBlock", func(call goja.FunctionCall) goja.Value {
+		name, state, err := world().Block(blockPos(call), blockLayer(call.Argument(3)))
+		if errors.Is(err, worldstate.ErrNoChunk) {
+			return goja.Null()
+		}
+		throw(err)
+		blockObj := v.runtime.NewObject()
+		blockObj.Set("name", name)
+		blockObj.Set("state", state)
+		return blockObj
+	})
+
+	// setBlock(x, y, z, name, state?, layer?)
+	obj.Set("setBlock", func(call goja.FunctionCall) goja.Value {
+		properties, err := blockProperties(call.Argument(4))
+		throw(err)
+		err = world().SetBlock(blockPos(call), blockLayer(call.Argument(5)), call.Argument(3).String(), properties)
+		if errors.Is(err, worldstate.ErrNoChunk) {
+			return v.runtime.ToValue(false)
+		}
+		throw(err)
+		return v.runtime.ToValue(true)
+	})
+
+	// fill([x, y, z], [x, y, z], name, state?, replace?)
+	obj.Set("fill", func(call goja.FunctionCall) goja.Value {
+		properties, err := blockProperties(call.Argument(3))
+		throw(err)
+		var replace string
+		if arg := call.Argument(4); !goja.IsUndefined(arg) && !goja.IsNull(arg) {
+			replace = arg.String()
+		}
+		changed, err := world().Fill(v.position(call.Argument(0)), v.position(call.Argument(1)), call.Argument(2).String(), properties, replace)
+		throw(err)
+		return v.runtime.ToValue(changed)
+	})
+
+	// getBlockEntity(x, y, z)
+	obj.Set("getBlockEntity", func(call goja.FunctionCall) goja.Value {
+		data, err := world().BlockEntity(blockPos(call))
+		if errors.Is(err, worldstate.ErrNoChunk) {
+			return goja.Null()
+		}
+		throw(err)
+		if data == nil {
+			return goja.Null()
+		}
+		return v.runtime.ToValue(data)
+	})
+
+	// setBlockEntity(x, y, z, data, merge?)
+	obj.Set("setBlockEntity", func(call goja.FunctionCall) goja.Value {
+		data, ok := call.Argument(3).Export().(map[string]any)
+		if !ok {
+			panic(v.runtime.NewTypeError("block entity data must be an object"))
+		}
+		w := world()
+		pos := blockPos(call)
+		if _, err := w.BlockEntity(pos); errors.Is(err, worldstate.ErrNoChunk) {
+			return v.runtime.ToValue(false)
+		}
+		throw(w.SetBlockNBT(pos, data, call.Argument(4).ToBoolean()))
+		return v.runtime.ToValue(true)
+	})
+
+	// removeBlockEntity(x, y, z)
+	obj.Set("removeBlockEntity", func(call goja.FunctionCall) goja.Value {
+		err := world().RemoveBlockEntity(blockPos(call))
+		if errors.Is(err, worldstate.ErrNoChunk) {
+			return v.runtime.ToValue(false)
+		}
+		throw(err)
+		return v.runtime.ToValue(true)
+	})
+
+	// getEntities(), the entities are copies so the world isnt changed while the proxy uses it
+	obj.Set("getEntities", func(call goja.FunctionCall) goja.Value {
+		var objs []any
+		for _, ent := range world().Entities() {
+			entObj := v.runtime.NewObject()
+			entObj.Set("Entity", ent)
+			entObj.Set("Metadata", newEntityDataObject(v.runtime, ent.Metadata))
+			objs = append(objs, entObj)
+		}
+		return v.runtime.NewArray(objs...)
+	})
+
+	// setEntity(entity), stores a changed entity from getEntities
+	obj.Set("setEntity", func(call goja.FunctionCall) goja.Value {
+		ent, ok := call.Argument(0).Export().(*entity.Entity)
+		if !ok {
+			panic(v.runtime.NewTypeError("not an entity from getEntities"))
+		}
+		return v.runtime.ToValue(world().SetEntity(ent))
+	})
+
+	// removeEntity(runtimeID)
+	obj.Set("removeEntity", func(call goja.FunctionCall) goja.Value {
+		return v.runtime.ToValue(world().RemoveEntity(uint64(call.Argument(0).ToInteger())))
+	})
+
+	return obj
+}
+
+// OnBeforeSave lets the script edit a world before it is written,
+// the world is already detached from the session so it is passed to the callback
+func (v *VM) OnBeforeSave(w *worldstate.World) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.CB.OnBeforeSave == nil {
+		return
+	}
+	err := v.call("BeforeSave", longTimeBudget, func() error {
+		v.CB.OnBeforeSave(v.newWorldObject(func() *worldstate.World { return w }), w.Name)
+		return nil
+	})
+	if err != nil {
+		v.log.Error(err)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"path"
@@ -330,3 +331,9 @@ func (s *SkinSaver) stealSkin() {
 		}
 	}
 }
+
+func init() {
+	proxy.RegisterHandler("skins", "Skin Saver", func(ctx context.Context) *proxy.Handler {
+		return NewSkinSaver(nil, nil, false, true)()
+	})
+}
@@ -0,0 +1,110 @@
+package worlds
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
+	"github.com/bedrock-tool/bedrocktool/utils/proxy"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+func (w *worldsHandler) setAreas(areas []worldstate.Area) {
+	w.areas = areas
+	w.currentWorld(func(world *worldstate.World) {
+		world.SetAreas(areas)
+	})
+}
+
+func (w *worldsHandler) playerBlockPos() (dimension int, x, z int32) {
+	pos := w.session.Player.Position
+	w.currentWorld(func(ws *worldstate.World) {
+		dimension, _ = world.DimensionID(ws.Dimension())
+	})
+	return dimension, int32(math.Floor(float64(pos.X()))), int32(math.Floor(float64(pos.Z())))
+}
+
+// areaCommand marks the areas chunks are kept in from where the player is standing
+func (w *worldsHandler) areaCommand(session *proxy.Session, args []string) bool {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list":
+		if len(w.areas) == 0 {
+			session.SendMessage("No areas, keeping every chunk")
+		}
+		for i, area := range w.areas {
+			session.SendMessage(fmt.Sprintf("%d: %s", i+1, area))
+		}
+
+	case "corner":
+		dimension, x, z := w.playerBlockPos()
+		if w.areaCorner == nil || w.areaCorner.dimension != dimension {
+			w.areaCorner = &areaCorner{dimension, x, z}
+			session.SendMessage(fmt.Sprintf("First corner at %d %d, go to the opposite corner and run this again", x, z))
+			return true
+		}
+		area := worldstate.NewArea(dimension, w.areaCorner.x, w.areaCorner.z, x, z)
+		w.areaCorner = nil
+		w.setAreas(append(slices.Clone(w.areas), area))
+		session.SendMessage(fmt.Sprintf("Added area %s", area))
+
+	case "radius":
+		if len(args) < 2 {
+			session.SendMessage("Usage: area radius <blocks>")
+			return false
+		}
+		radius, err := strconv.Atoi(args[1])
+		if err != nil || radius < 0 {
+			session.SendMessage("Radius has to be a number of blocks")
+			return false
+		}
+		dimension, x, z := w.playerBlockPos()
+		r := int32(radius)
+		area := worldstate.NewArea(dimension, x-r, z-r, x+r, z+r)
+		w.setAreas(append(slices.Clone(w.areas), area))
+		session.SendMessage(fmt.Sprintf("Added area %s", area))
+
+	case "remove":
+		if len(args) < 2 {
+			session.SendMessage("Usage: area remove <number>")
+			return false
+		}
+		i, err := strconv.Atoi(args[1])
+		if err != nil || i < 1 || i > len(w.areas) {
+			session.SendMessage(fmt.Sprintf("No area %s", args[1]))
+			return false
+		}
+		w.setAreas(slices.Delete(slices.Clone(w.areas), i-1, i))
+		session.SendMessage(fmt.Sprintf("Removed area %d", i))
+
+	case "clear":
+		w.areaCorner = nil
+		w.setAreas(nil)
+		session.SendMessage("Removed all areas, keeping every chunk")
+
+	default:
+		session.SendMessage("Usage: area [list|corner|radius <blocks>|remove <number>|clear]")
+		return false
+	}
+	return true
+}
+
+type areaCorner struct {
+	dimension int
+	x, z      int32
+}
+
+func (w *worldsHandler) addAreaCommand(session *proxy.Session) {
+	session.AddCommand(func(args []string) bool {
+		return w.areaCommand(session, args)
+	}, protocol.Command{
+		Name:        "area",
+		Description: "limit the downloaded chunks to areas marked from where you stand",
+	})
+}
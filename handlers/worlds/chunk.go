@@ -87,16 +87,26 @@ func (w *worldsHandler) handleLevelChunk(pk *packet.LevelChunk, timeReceived tim
 			offsetTable = append(offsetTable, protocol.SubChunkOffset{0, y, 0})
 		}
 
+		offsets := offsetTable[:min(max+1, len(offsetTable))]
+		subYs := make([]int, 0, len(offsets))
+		for _, offset := range offsets {
+			subYs = append(subYs, int(offset[1]))
+		}
+		w.worldState.ExpectSubChunks(pos, subYs)
+
 		dimId, _ := world.DimensionID(w.worldState.Dimension())
 		_ = w.session.Server.WritePacket(&packet.SubChunkRequest{
 			Dimension: int32(dimId),
 			Position: protocol.SubChunkPos{
 				pk.Position.X(), 0, pk.Position.Z(),
 			},
-			Offsets: offsetTable[:min(max+1, len(offsetTable))],
+			Offsets: offsets,
 		})
 	default:
+		// all sub chunks are in the chunk
+		w.worldState.ExpectSubChunks(pos, nil)
 	}
+	w.mapUI.SetMissing(pos, w.worldState.HasMissingSubChunks(pos))
 
 	err = w.worldState.StoreChunk(pos, ch)
 	if err != nil {
@@ -109,10 +119,10 @@ func (w *worldsHandler) handleLevelChunk(pk *packet.LevelChunk, timeReceived tim
 
 	total, active := w.worldState.EntityCounts(w.serverState.getEntityRenderDistance())
 	w.session.SendPopup(locale.Locm("popup_chunk_count", locale.Strmap{
-		"Chunks":   len(w.worldState.StoredChunks),
+		"Chunks":   w.worldState.ChunkCount(),
 		"Entities": fmt.Sprintf("%d (%d)", total, active),
 		"Name":     w.worldState.Name,
-	}, len(w.worldState.StoredChunks)))
+	}, w.worldState.ChunkCount()))
 
 	return nil
 }
@@ -123,7 +133,8 @@ func (w *worldsHandler) processSubChunk(pk *packet.SubChunk) error {
 
 	var chunks = make(map[world.ChunkPos]*worldstate.Chunk)
 	for _, ent := range pk.SubChunkEntries {
-		if ent.Result != protocol.SubChunkResultSuccess {
+		// failed ones stay missing
+		if ent.Result != protocol.SubChunkResultSuccess && ent.Result != protocol.SubChunkResultSuccessAllAir {
 			continue
 		}
 		var (
@@ -144,7 +155,11 @@ func (w *worldsHandler) processSubChunk(pk *packet.SubChunk) error {
 			return err
 		}
 		if !ok {
-			return errors.New("bug check: subchunk received before chunk")
+			if w.worldState.HasMissingSubChunks(pos) {
+				return errors.New("bug check: subchunk received before chunk")
+			}
+			// not kept because its outside of the areas
+			continue
 		}
 		chunks[pos] = ch
 	}
@@ -164,7 +179,9 @@ func (w *worldsHandler) processSubChunk(pk *packet.SubChunk) error {
 
 		switch ent.Result {
 		case protocol.SubChunkResultSuccessAllAir:
+			w.worldState.ReceivedSubChunk(pos, int(absY))
 		case protocol.SubChunkResultSuccess:
+			w.worldState.ReceivedSubChunk(pos, int(absY))
 			buf := bytes.NewBuffer(ent.RawPayload)
 			index := uint8(absY)
 			sub, err := chunk.DecodeSubChunk(
@@ -198,6 +215,7 @@ func (w *worldsHandler) processSubChunk(pk *packet.SubChunk) error {
 	}
 
 	for pos, ch := range chunks {
+		w.mapUI.SetMissing(pos, w.worldState.HasMissingSubChunks(pos))
 		w.worldState.StoreChunk(pos, ch)
 	}
 	if w.scripting != nil {
@@ -0,0 +1,142 @@
+package worlds
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
+	"github.com/bedrock-tool/bedrocktool/ui/messages"
+	"github.com/bedrock-tool/bedrocktool/utils/proxy"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+// arrows relative to where the player looks, clockwise from ahead
+var directionArrows = [8]string{"↑", "↗", "→", "↘", "↓", "↙", "←", "↖"}
+
+// compass names by yaw, which is 0 facing south and 90 facing west
+var compassNames = [8]string{"south", "south-west", "west", "north-west", "north", "north-east", "east", "south-east"}
+
+// octant rounds an angle in degrees to one of 8 directions
+func octant(degrees float64) int {
+	return int(math.Round(degrees/45)+8) % 8
+}
+
+// directionHint describes which way to walk to get dx dz blocks away while looking at yaw
+func directionHint(yaw float32, dx, dz float64) string {
+	target := math.Atan2(-dx, dz) * 180 / math.Pi
+	relative := math.Mod(target-float64(yaw), 360)
+	if relative < 0 {
+		relative += 360
+	}
+	return directionArrows[octant(relative)] + " " + compassNames[octant(math.Mod(target+360, 360))]
+}
+
+// coverageAreas are the areas in the dimension that should be downloaded,
+// everything the map has drawn so far if there are none
+func (w *worldsHandler) coverageAreas(dimension int) []worldstate.Area {
+	var areas []worldstate.Area
+	for _, area := range w.areas {
+		if area.Dimension == dimension {
+			areas = append(areas, area)
+		}
+	}
+	if len(areas) > 0 {
+		return areas
+	}
+
+	w.mapUI.mu.Lock()
+	defer w.mapUI.mu.Unlock()
+	if len(w.mapUI.renderedChunks) == 0 {
+		return nil
+	}
+	min, max := w.mapUI.GetBounds()
+	return []worldstate.Area{{
+		Dimension: dimension,
+		Min:       world.ChunkPos(min),
+		Max:       world.ChunkPos(max),
+	}}
+}
+
+// startCoverage shows where chunks are still missing every second
+func (w *worldsHandler) startCoverage() {
+	if w.coverageCancel != nil {
+		w.coverageCancel()
+	}
+	var ctx context.Context
+	ctx, w.coverageCancel = context.WithCancel(w.ctx)
+	go func() {
+		t := time.NewTicker(1 * time.Second)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			w.showCoverage()
+		}
+	}()
+}
+
+func (w *worldsHandler) stopCoverage() {
+	if w.coverageCancel != nil {
+		w.coverageCancel()
+		w.coverageCancel = nil
+	}
+	messages.SendEvent(&messages.EventCoverage{})
+}
+
+func (w *worldsHandler) showCoverage() {
+	dimension, x, z := w.playerBlockPos()
+	areas := w.coverageAreas(dimension)
+	if len(areas) == 0 {
+		return
+	}
+
+	var coverage worldstate.Coverage
+	w.currentWorld(func(ws *worldstate.World) {
+		coverage = ws.Coverage(areas, world.ChunkPos{x >> 4, z >> 4})
+	})
+	if coverage.Total == 0 {
+		return
+	}
+	messages.SendEvent(&messages.EventCoverage{
+		Received:  coverage.Received,
+		Total:     coverage.Total,
+		Target:    protocol.ChunkPos(coverage.Nearest),
+		HasTarget: coverage.HasMissing,
+	})
+
+	percent := coverage.Received * 100 / coverage.Total
+	if !coverage.HasMissing {
+		w.session.SendPopup(fmt.Sprintf("§aCoverage %d%%, nothing missing", percent))
+		return
+	}
+	// to the middle of the missing chunk
+	dx := float64(coverage.Nearest[0]<<4+8) - float64(x)
+	dz := float64(coverage.Nearest[1]<<4+8) - float64(z)
+	w.session.SendPopup(fmt.Sprintf("§eCoverage %d%% §7(%d/%d) §fmissing %s %.0fm",
+		percent, coverage.Received, coverage.Total,
+		directionHint(w.session.Player.Yaw, dx, dz), math.Hypot(dx, dz),
+	))
+}
+
+func (w *worldsHandler) addCoverageCommand(session *proxy.Session) {
+	session.AddCommand(func(args []string) bool {
+		w.settings.Coverage = !w.settings.Coverage
+		if w.settings.Coverage {
+			w.startCoverage()
+			session.SendMessage("Showing the way to missing chunks")
+		} else {
+			w.stopCoverage()
+			session.SendMessage("Stopped showing missing chunks")
+		}
+		return true
+	}, protocol.Command{
+		Name:        "coverage",
+		Description: "toggle showing how much of the areas or map is downloaded and where chunks are missing",
+	})
+}
@@ -1,7 +1,9 @@
 package entity
 
 import (
+	"maps"
 	"math"
+	"slices"
 
 	"github.com/df-mc/dragonfly/server/world/chunk"
 	"github.com/go-gl/mathgl/mgl32"
@@ -44,6 +46,20 @@ type Entity struct {
 	LastTeleport    int
 }
 
+// Clone returns a copy that can be changed without changing s
+func (s *Entity) Clone() *Entity {
+	c := *s
+	c.Metadata = maps.Clone(s.Metadata)
+	c.Properties = maps.Clone(s.Properties)
+	c.PropertiesOverridden = maps.Clone(s.PropertiesOverridden)
+	c.Tags = slices.Clone(s.Tags)
+	c.Inventory = make(map[byte]map[byte]protocol.ItemInstance, len(s.Inventory))
+	for window, items := range s.Inventory {
+		c.Inventory[window] = maps.Clone(items)
+	}
+	return &c
+}
+
 type EntityPropertyDef struct {
 	Type int32
 	Name string
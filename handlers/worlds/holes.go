@@ -0,0 +1,70 @@
+package worlds
+
+import (
+	"context"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// how many columns are requested again per second
+const holeRequestsPerTick = 8
+
+// startFillHoles periodically requests sub chunks that were never received again
+func (w *worldsHandler) startFillHoles() {
+	if w.fillCancel != nil {
+		w.fillCancel()
+	}
+	var ctx context.Context
+	ctx, w.fillCancel = context.WithCancel(w.ctx)
+	go func() {
+		t := time.NewTicker(1 * time.Second)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			w.requestMissingSubChunks()
+		}
+	}()
+}
+
+func (w *worldsHandler) requestMissingSubChunks() {
+	radius := w.serverState.realChunkRadius
+	if radius == 0 || w.session.Server == nil {
+		return
+	}
+	playerPos := w.session.Player.Position
+	center := world.ChunkPos{int32(playerPos.X()) >> 4, int32(playerPos.Z()) >> 4}
+
+	var missing map[world.ChunkPos][]int
+	var dimension int
+	w.currentWorld(func(ws *worldstate.World) {
+		if ws == nil {
+			return
+		}
+		missing = ws.RetryMissingSubChunks(center, radius, holeRequestsPerTick)
+		dimension, _ = world.DimensionID(ws.Dimension())
+	})
+
+	for pos, ys := range missing {
+		offsets := make([]protocol.SubChunkOffset, 0, len(ys))
+		for _, y := range ys {
+			offsets = append(offsets, protocol.SubChunkOffset{0, int8(y), 0})
+		}
+		err := w.session.Server.WritePacket(&packet.SubChunkRequest{
+			Dimension: int32(dimension),
+			Position:  protocol.SubChunkPos{pos[0], 0, pos[1]},
+			Offsets:   offsets,
+		})
+		if err != nil {
+			w.log.Error(err)
+			return
+		}
+	}
+}
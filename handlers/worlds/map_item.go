@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"image"
+	"image/color"
 	"image/draw"
 	"math"
 	"net"
@@ -45,6 +46,7 @@ func (m *MapUI) GetBounds() (Min, Max protocol.ChunkPos) {
 		return
 	}
 	Min = protocol.ChunkPos{math.MaxInt32, math.MaxInt32}
+	Max = protocol.ChunkPos{math.MinInt32, math.MinInt32}
 	for chunk := range m.renderedChunks {
 		Min[0] = min(Min[0], chunk[0])
 		Min[1] = min(Min[1], chunk[1])
@@ -65,7 +67,9 @@ type MapUI struct {
 	renderQueue    []*renderElem
 	renderedChunks map[protocol.ChunkPos]*image.RGBA // prerendered chunks
 	oldRendered    map[protocol.ChunkPos]*image.RGBA
+	missing        map[protocol.ChunkPos]bool // chunks that are missing sub chunks
 	ticker         *time.Ticker
+	cancel         context.CancelFunc
 	w              *worldsHandler
 
 	ChunkRenderer *utils.ChunkRenderer
@@ -87,6 +91,7 @@ func NewMapUI(w *worldsHandler) *MapUI {
 		zoomLevel:      16,
 		renderedChunks: make(map[protocol.ChunkPos]*image.RGBA),
 		oldRendered:    make(map[protocol.ChunkPos]*image.RGBA),
+		missing:        make(map[protocol.ChunkPos]bool),
 		needRedraw:     true,
 		w:              w,
 		haveColors:     make(chan struct{}),
@@ -118,11 +123,13 @@ func (m *MapUI) SetEnabled(enabled bool) {
 	}
 }
 
-func (m *MapUI) mapUpdater(ctx context.Context) {
+func (m *MapUI) mapUpdater(ctx context.Context, ticker *time.Ticker) {
 	var oldPos mgl32.Vec3
-	for range m.ticker.C {
-		if ctx.Err() != nil {
+	for {
+		select {
+		case <-ctx.Done():
 			return
+		case <-ticker.C:
 		}
 		newPos := m.w.session.Player.Position
 		if int(oldPos.X()) != int(newPos.X()) || int(oldPos.Z()) != int(newPos.Z()) {
@@ -182,19 +189,11 @@ func (m *MapUI) itemSender() {
 }
 
 func (m *MapUI) Start(ctx context.Context) {
-	// init map
-	err := m.w.session.ClientWritePacket(&packet.ClientBoundMapItemData{
-		MapID:          ViewMapID,
-		Scale:          4,
-		MapsIncludedIn: []int64{ViewMapID},
-		UpdateFlags:    packet.MapUpdateFlagInitialisation,
-	})
-	if err != nil {
+	if err := m.sendInit(); err != nil {
 		m.log.Error(err)
 		return
 	}
 
-	m.ticker = time.NewTicker(33 * time.Millisecond)
 	m.ChunkRenderer = utils.NewChunkRenderer(m.w.serverState.blocks)
 	go func() {
 		m.ChunkRenderer.ResolveColors(
@@ -203,11 +202,40 @@ func (m *MapUI) Start(ctx context.Context) {
 		)
 		close(m.haveColors)
 	}()
-	go m.mapUpdater(ctx)
+	m.run(ctx)
+}
+
+// Resume starts sending the map to a client that came back after a reconnect, rendered chunks are kept
+func (m *MapUI) Resume(ctx context.Context) {
+	m.Stop()
+	if err := m.sendInit(); err != nil {
+		m.log.Error(err)
+		return
+	}
+	m.SchedRedraw()
+	m.run(ctx)
+}
+
+func (m *MapUI) sendInit() error {
+	return m.w.session.ClientWritePacket(&packet.ClientBoundMapItemData{
+		MapID:          ViewMapID,
+		Scale:          4,
+		MapsIncludedIn: []int64{ViewMapID},
+		UpdateFlags:    packet.MapUpdateFlagInitialisation,
+	})
+}
+
+func (m *MapUI) run(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.ticker = time.NewTicker(33 * time.Millisecond)
+	go m.mapUpdater(ctx, m.ticker)
 	go m.itemSender()
 }
 
 func (m *MapUI) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
 	if m.ticker != nil {
 		m.ticker.Stop()
 	}
@@ -218,6 +246,7 @@ func (m *MapUI) Reset() {
 	m.mu.Lock()
 	m.renderedChunks = make(map[protocol.ChunkPos]*image.RGBA)
 	m.oldRendered = make(map[protocol.ChunkPos]*image.RGBA)
+	m.missing = make(map[protocol.ChunkPos]bool)
 	messages.SendEvent(&messages.EventResetMap{})
 	m.mu.Unlock()
 	m.SchedRedraw()
@@ -244,6 +273,9 @@ func (m *MapUI) processQueue() []protocol.ChunkPos {
 	for _, r := range m.renderQueue {
 		if r.ch != nil {
 			img := m.ChunkRenderer.Chunk2Img(r.ch)
+			if m.missing[r.pos] {
+				drawMissingOverlay(img)
+			}
 			m.renderedChunks[r.pos] = img
 			updatedChunks = append(updatedChunks, r.pos)
 		} else {
@@ -335,3 +367,28 @@ func (m *MapUI) SetChunk(pos world.ChunkPos, ch *chunk.Chunk) {
 	})
 	m.SchedRedraw()
 }
+
+// SetMissing marks a chunk to be drawn with stripes because its missing sub chunks, applies on the next SetChunk
+func (m *MapUI) SetMissing(pos world.ChunkPos, missing bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if missing {
+		m.missing[(protocol.ChunkPos)(pos)] = true
+	} else {
+		delete(m.missing, (protocol.ChunkPos)(pos))
+	}
+}
+
+var missingColor = color.RGBA{0xff, 0, 0, 0xff}
+
+// drawMissingOverlay draws red stripes over a chunk image
+func drawMissingOverlay(img *image.RGBA) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if (x+y)%4 == 0 {
+				img.SetRGBA(x, y, missingColor)
+			}
+		}
+	}
+}
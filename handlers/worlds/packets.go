@@ -81,21 +81,53 @@ func (w *worldsHandler) packetHandlerPreLogin(_pk packet.Packet, timeReceived ti
 
 			dim, _ := world.DimensionByID(int(pk.Dimension))
 			w.worldStateMu.Lock()
-			if pk.WorldName != "" {
-				w.worldState.Name = pk.WorldName
+			resumedIn := w.worldState.Dimension()
+			if w.serverState.resuming {
+				// the world is already open, only what the new connection might have changed is applied
+				w.worldState.BlockRegistry = br
+				w.worldState.UseHashedRids = w.serverState.useHashedRids
+			} else {
+				if pk.WorldName != "" {
+					w.worldState.Name = pk.WorldName
+				}
+				w.worldState.SetDimension(dim)
+				w.openWorldState()
 			}
-			w.worldState.SetDimension(dim)
 			w.worldState.SetTime(timeReceived, int(pk.Time))
-			w.openWorldState()
 			w.worldStateMu.Unlock()
+
+			// the server might have put the player somewhere else, like a ChangeDimension
+			if w.serverState.resuming && dim != resumedIn {
+				if w.settings.SingleWorld {
+					w.changeDimension(dim)
+				} else {
+					w.SaveAndReset(false, dim)
+				}
+			}
+			w.serverState.resuming = false
 		}
 
 	case *packet.DimensionData:
 		for _, dd := range pk.Definitions {
-			if dd.Name == "minecraft:overworld" {
-				w.serverState.dimensions[0] = dd
+			id, ok := worldstate.DimensionIDByName(dd.Name)
+			if !ok {
+				if w.settings.SingleWorld {
+					// a world only has the vanilla dimensions, mixing one in would save it over another
+					w.settings.SingleWorld = false
+					msg := fmt.Sprintf("The server has the custom dimension %s, -single-world can't keep it in one world so every dimension is saved as its own world", dd.Name)
+					w.log.Error(msg)
+					w.session.SendMessage(msg)
+					continue
+				}
+				w.log.Warnf("Unknown dimension %s, its chunks are saved with the vanilla height", dd.Name)
+				continue
 			}
+			w.serverState.dimensions[id] = dd
 		}
+		// the height of the current dimension might have changed
+		w.currentWorld(func(world *worldstate.World) {
+			world.SetDimension(world.Dimension())
+		})
 
 	case *packet.ItemRegistry:
 		world.InsertCustomItems(pk.Items)
@@ -148,7 +180,11 @@ func (w *worldsHandler) packetHandlerIngame(_pk packet.Packet, toServer bool, ti
 
 	case *packet.ChangeDimension:
 		dim, _ := world.DimensionByID(int(pk.Dimension))
-		w.SaveAndReset(false, dim)
+		if w.settings.SingleWorld {
+			w.changeDimension(dim)
+		} else {
+			w.SaveAndReset(false, dim)
+		}
 
 	case *packet.LevelChunk:
 		err := w.handleLevelChunk(pk, timeReceived)
@@ -209,7 +245,7 @@ func (w *worldsHandler) packetHandlerIngame(_pk packet.Packet, toServer bool, ti
 
 	case *packet.AddActor:
 		w.currentWorld(func(world *worldstate.World) {
-			err := world.ActEntity(pk.EntityRuntimeID, true, func(ent *entity.Entity) error {
+			err := world.ActAddedEntity(pk.EntityRuntimeID, pk.EntityUniqueID, func(ent *entity.Entity) error {
 				isNew := ent.EntityType == ""
 
 				// get samples of the entity render distance
@@ -625,7 +661,7 @@ func (w *worldsHandler) packetHandlerIngame(_pk packet.Packet, toServer bool, ti
 						return
 					}
 				}
-				world.QueueBlockUpdate(pk.Position, rid, uint8(pk.Layer))
+				world.QueueBlockUpdate(pk.Position, rid, uint8(pk.Layer), timeReceived)
 			})
 
 		case *packet.UpdateBlockSynced:
@@ -639,7 +675,7 @@ func (w *worldsHandler) packetHandlerIngame(_pk packet.Packet, toServer bool, ti
 						return
 					}
 				}
-				world.QueueBlockUpdate(pk.Position, rid, uint8(pk.Layer))
+				world.QueueBlockUpdate(pk.Position, rid, uint8(pk.Layer), timeReceived)
 			})
 
 		case *packet.UpdateSubChunkBlocks:
@@ -654,7 +690,7 @@ func (w *worldsHandler) packetHandlerIngame(_pk packet.Packet, toServer bool, ti
 							return
 						}
 					}
-					world.QueueBlockUpdate(block.BlockPos, rid, uint8(0))
+					world.QueueBlockUpdate(block.BlockPos, rid, uint8(0), timeReceived)
 				}
 			})
 		}
@@ -664,11 +700,6 @@ func (w *worldsHandler) packetHandlerIngame(_pk packet.Packet, toServer bool, ti
 }
 
 func (w *worldsHandler) packetHandler(_ *proxy.Session, pk packet.Packet, toServer bool, timeReceived time.Time, preLogin bool) (packet.Packet, error) {
-	if w.scripting != nil {
-		if !w.scripting.OnPacket(pk, toServer, timeReceived) {
-			return nil, nil
-		}
-	}
 	if preLogin {
 		return w.packetHandlerPreLogin(pk, timeReceived)
 	}
@@ -0,0 +1,47 @@
+package worlds
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
+	"github.com/bedrock-tool/bedrocktool/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// RecoverWorlds turns worlds that were left unsaved by a crash into .mcworld files
+func RecoverWorlds() {
+	log := logrus.WithField("part", "WorldsHandler")
+	root := utils.PathData("worlds")
+	servers, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, server := range servers {
+		if !server.IsDir() {
+			continue
+		}
+		worlds, err := os.ReadDir(filepath.Join(root, server.Name()))
+		if err != nil {
+			continue
+		}
+		for _, entry := range worlds {
+			folder := filepath.Join(root, server.Name(), entry.Name())
+			if !entry.IsDir() || !worldstate.HasJournal(folder) {
+				continue
+			}
+			log.Infof("Recovering %s that wasnt saved", folder)
+			recovered, err := worldstate.Recover(folder)
+			if err != nil {
+				log.WithError(err).Errorf("Failed to recover %s", folder)
+				continue
+			}
+			filename, err := zipWorld(recovered)
+			if err != nil {
+				log.WithError(err).Errorf("Failed to recover %s", folder)
+				continue
+			}
+			log.Infof("Recovered %s", filename)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package worlds
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
+	"github.com/bedrock-tool/bedrocktool/utils/merge"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/mcdb"
+	"github.com/df-mc/goleveldb/leveldb/opt"
+)
+
+type dimensionReport struct {
+	Chunks int `json:"chunks"`
+	// bounding box in blocks
+	MinX int32 `json:"min_x"`
+	MinZ int32 `json:"min_z"`
+	MaxX int32 `json:"max_x"`
+	MaxZ int32 `json:"max_z"`
+}
+
+type entityReport struct {
+	// entities seen in this session and how many were still around when saving
+	Total  int            `json:"total"`
+	Active int            `json:"active"`
+	Types  map[string]int `json:"types"`
+}
+
+type packReport struct {
+	Name    string `json:"name"`
+	UUID    string `json:"uuid"`
+	Version string `json:"version"`
+}
+
+// worldReport is written next to each saved world so they can be indexed without opening them
+type worldReport struct {
+	Name            string                      `json:"name"`
+	Server          string                      `json:"server"`
+	GameVersion     string                      `json:"game_version"`
+	Started         time.Time                   `json:"started"`
+	Saved           time.Time                   `json:"saved"`
+	DurationSeconds int64                       `json:"duration_seconds"`
+	Dimensions      map[string]*dimensionReport `json:"dimensions"`
+	Entities        entityReport                `json:"entities"`
+	BlockEntities   map[string]int              `json:"block_entities"`
+	ResourcePacks   []packReport                `json:"resource_packs"`
+	PartialChunks   []worldstate.PartialChunk   `json:"partial_chunks"`
+}
+
+// reportFilename is where the report of the world saved to folder goes, <world>.report.json next to <world>.mcworld.
+// worlds from one server share a folder, so a plain report.json would be overwritten by the next world
+func reportFilename(folder string) string {
+	return folder + ".report.json"
+}
+
+// writeReport reads the saved world and writes its report
+func (w *worldsHandler) writeReport(worldState *worldstate.World, filename string) error {
+	report := worldReport{
+		Name:          worldState.Name,
+		Server:        w.serverState.serverName,
+		GameVersion:   w.session.Server.GameData().BaseGameVersion,
+		Started:       worldState.Started(),
+		Saved:         time.Now(),
+		Dimensions:    make(map[string]*dimensionReport),
+		BlockEntities: make(map[string]int),
+		ResourcePacks: []packReport{},
+		PartialChunks: worldState.PartialChunks(),
+	}
+	report.DurationSeconds = int64(report.Saved.Sub(report.Started).Seconds())
+	report.Entities.Total, report.Entities.Active = worldState.EntityCounts(w.serverState.getEntityRenderDistance())
+	report.Entities.Types = make(map[string]int)
+	for _, pack := range worldState.ResourcePacks {
+		report.ResourcePacks = append(report.ResourcePacks, packReport{
+			Name:    pack.Name(),
+			UUID:    pack.UUID().String(),
+			Version: pack.Version(),
+		})
+	}
+
+	db, err := mcdb.Config{
+		Log: slog.Default(),
+		Blocks: &merge.BlockRegistry{
+			BlockRegistry: world.DefaultBlockRegistry,
+			Rids:          make(map[uint32]merge.Block),
+		},
+		LDBOptions: &opt.Options{
+			ReadOnly: true,
+		},
+	}.Open(worldState.Folder)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	it := db.NewColumnIterator(nil)
+	defer it.Release()
+	for it.Next() {
+		pos := it.Position()
+		name := fmt.Sprint(it.Dimension())
+		dim, ok := report.Dimensions[name]
+		if !ok {
+			dim = &dimensionReport{MinX: math.MaxInt32, MinZ: math.MaxInt32, MaxX: math.MinInt32, MaxZ: math.MinInt32}
+			report.Dimensions[name] = dim
+		}
+		dim.Chunks++
+		dim.MinX = min(dim.MinX, pos[0]<<4)
+		dim.MinZ = min(dim.MinZ, pos[1]<<4)
+		dim.MaxX = max(dim.MaxX, pos[0]<<4+15)
+		dim.MaxZ = max(dim.MaxZ, pos[1]<<4+15)
+
+		col := it.Column()
+		for _, ent := range col.Entities {
+			id, _ := ent.Data["identifier"].(string)
+			report.Entities.Types[id]++
+		}
+		for _, be := range col.BlockEntities {
+			id, _ := be.Data["id"].(string)
+			report.BlockEntities[id]++
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o666)
+}
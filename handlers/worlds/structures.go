@@ -0,0 +1,93 @@
+package worlds
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
+	"github.com/bedrock-tool/bedrocktool/utils"
+	"github.com/bedrock-tool/bedrocktool/utils/proxy"
+	"github.com/bedrock-tool/bedrocktool/utils/schematic"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+// the player position is at the eyes
+const playerEyeHeight = 1.62
+
+// structureCommand marks the corners of a structure and saves it from the downloaded chunks
+func (w *worldsHandler) structureCommand(session *proxy.Session, args []string) bool {
+	if len(args) == 0 {
+		args = []string{"help"}
+	}
+
+	switch args[0] {
+	case "pos1", "pos2":
+		pos := session.Player.Position
+		corner := cube.Pos{
+			int(math.Floor(float64(pos.X()))),
+			int(math.Floor(float64(pos.Y() - playerEyeHeight))),
+			int(math.Floor(float64(pos.Z()))),
+		}
+		if len(args) > 1 {
+			var err error
+			corner, err = schematic.ParsePos(args[1])
+			if err != nil {
+				session.SendMessage(err.Error())
+				return false
+			}
+		}
+		i := 0
+		if args[0] == "pos2" {
+			i = 1
+		}
+		w.structureCorners[i] = &corner
+		session.SendMessage(fmt.Sprintf("Set %s to %d %d %d", args[0], corner[0], corner[1], corner[2]))
+
+	case "save":
+		if len(args) < 2 {
+			session.SendMessage("Usage: structure save <name> [entities]")
+			return false
+		}
+		if w.structureCorners[0] == nil || w.structureCorners[1] == nil {
+			session.SendMessage("Set both corners with structure pos1 and structure pos2 first")
+			return false
+		}
+		filename := filepath.Base(args[1])
+		if !slices.Contains(schematic.Formats, filepath.Ext(filename)) {
+			filename += ".mcstructure"
+		}
+		withEntities := len(args) > 2 && args[2] == "entities"
+
+		var structure *schematic.Structure
+		var err error
+		w.currentWorld(func(world *worldstate.World) {
+			structure, err = world.Structure(*w.structureCorners[0], *w.structureCorners[1], withEntities)
+		})
+		if err == nil {
+			err = structure.WriteFile(utils.PathData("structures", filename))
+		}
+		if err != nil {
+			w.log.Error(err)
+			session.SendMessage(fmt.Sprintf("Failed to save %s: %s", filename, err))
+			return false
+		}
+		session.SendMessage(fmt.Sprintf("Saved %s %s", filename, structure))
+
+	default:
+		session.SendMessage("Usage: structure [pos1 [x,y,z]|pos2 [x,y,z]|save <name> [entities]]")
+		return false
+	}
+	return true
+}
+
+func (w *worldsHandler) addStructureCommand(session *proxy.Session) {
+	session.AddCommand(func(args []string) bool {
+		return w.structureCommand(session, args)
+	}, protocol.Command{
+		Name:        "structure",
+		Description: "save a box of the downloaded world as .mcstructure or .schem",
+	})
+}
@@ -11,18 +11,21 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/bedrock-tool/bedrocktool/handlers/scripting"
 	"github.com/bedrock-tool/bedrocktool/handlers/worlds/entity"
-	"github.com/bedrock-tool/bedrocktool/handlers/worlds/scripting"
 	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
 	"github.com/bedrock-tool/bedrocktool/locale"
 	"github.com/bedrock-tool/bedrocktool/ui/messages"
 	"github.com/bedrock-tool/bedrocktool/utils"
+	"github.com/bedrock-tool/bedrocktool/utils/anvil"
 	"github.com/bedrock-tool/bedrocktool/utils/behaviourpack"
 	"github.com/bedrock-tool/bedrocktool/utils/proxy"
 	"github.com/bedrock-tool/bedrocktool/utils/resourcepack"
 	"github.com/google/uuid"
 
+	"github.com/df-mc/dragonfly/server/block/cube"
 	"github.com/df-mc/dragonfly/server/world"
 	_ "github.com/df-mc/dragonfly/server/world/biome"
 	"github.com/sandertv/gophertunnel/minecraft"
@@ -38,16 +41,34 @@ type WorldSettings struct {
 	SaveInventories bool
 	ExcludedMobs    []string
 	ChunkRadius     int32
-	Script          string
 	Players         bool
 	BlockUpdates    bool
 	EntityCulling   bool
+	// world folder the first world is added to instead of starting a new one
+	Resume      string
+	MergePolicy worldstate.MergePolicy
+	// only chunks in these are kept, all if empty
+	Areas []worldstate.Area
+	// also write a java edition copy next to the world
+	Java bool
+	// how often downloaded chunks and entities are written to disk, default if 0
+	FlushInterval time.Duration
+	// record block updates with their time next to the world
+	History bool
+	// keep every dimension in one world instead of a world per dimension visit
+	SingleWorld bool
+	// request sub chunks that never arrived again
+	FillHoles bool
+	// show the way to chunks that are still missing in the areas or map
+	Coverage bool
 }
 
 type serverState struct {
-	serverName      string
-	useHashedRids   bool
-	haveStartGame   bool
+	serverName    string
+	useHashedRids bool
+	haveStartGame bool
+	// the StartGame after a reconnect continues the world instead of opening it
+	resuming        bool
 	worldCounter    int
 	worldName       string
 	realChunkRadius int32
@@ -96,6 +117,14 @@ type worldsHandler struct {
 
 	serverState serverState
 	settings    WorldSettings
+
+	areas      []worldstate.Area
+	areaCorner *areaCorner
+
+	structureCorners [2]*cube.Pos
+
+	fillCancel     context.CancelFunc
+	coverageCancel context.CancelFunc
 }
 
 type itemContainer struct {
@@ -112,12 +141,19 @@ func NewWorldsHandler(ctx context.Context, settings WorldSettings) func() *proxy
 		settings.ChunkRadius = 76
 	}
 
+	var previous *worldsHandler
 	return func() *proxy.Handler {
-		w := &worldsHandler{
-			ctx:      ctx,
-			log:      logrus.WithField("part", "WorldsHandler"),
-			settings: settings,
+		// after a reconnect the same handler keeps collecting into its world
+		w := previous
+		if w == nil || !w.session.Reconnecting() {
+			w = &worldsHandler{
+				ctx:      ctx,
+				log:      logrus.WithField("part", "WorldsHandler"),
+				settings: settings,
+				areas:    settings.Areas,
+			}
 		}
+		previous = w
 
 		return &proxy.Handler{
 			Name: "Worlds",
@@ -132,6 +168,9 @@ func NewWorldsHandler(ctx context.Context, settings WorldSettings) func() *proxy
 
 			PacketCallback: w.packetHandler,
 			OnSessionEnd: func(s *proxy.Session, wg *sync.WaitGroup) {
+				if s.Reconnecting() {
+					return
+				}
 				wg.Add(1)
 				go func() {
 					defer wg.Done()
@@ -150,7 +189,48 @@ func NewWorldsHandler(ctx context.Context, settings WorldSettings) func() *proxy
 	}
 }
 
+// registered worlds handlers by the context of the command, so one carries over reconnects like the worlds command
+var (
+	registeredWorlds   = map[context.Context]func() *proxy.Handler{}
+	registeredWorldsMu sync.Mutex
+)
+
+func init() {
+	proxy.RegisterHandler("worlds", "Worlds", func(ctx context.Context) *proxy.Handler {
+		registeredWorldsMu.Lock()
+		registered, ok := registeredWorlds[ctx]
+		if !ok {
+			registered = NewWorldsHandler(ctx, WorldSettings{
+				VoidGen:         true,
+				SaveEntities:    true,
+				SaveInventories: true,
+			})
+			registeredWorlds[ctx] = registered
+			context.AfterFunc(ctx, func() {
+				registeredWorldsMu.Lock()
+				delete(registeredWorlds, ctx)
+				registeredWorldsMu.Unlock()
+			})
+		}
+		registeredWorldsMu.Unlock()
+		return registered()
+	})
+}
+
 func (w *worldsHandler) onSessionStart(session *proxy.Session, serverName string) error {
+	if session.Resumed() && w.worldState != nil {
+		// the session is set once connected, until then saving uses the old one.
+		// the new connection starts the game again with its own runtime and window ids
+		w.serverState.haveStartGame = false
+		w.serverState.resuming = true
+		clear(w.serverState.openItemContainers)
+		w.currentWorld(func(world *worldstate.World) {
+			world.DetachEntities()
+		})
+		w.addCommands(session)
+		return nil
+	}
+
 	w.session = session
 	w.serverState = serverState{
 		serverName:           serverName,
@@ -167,24 +247,32 @@ func (w *worldsHandler) onSessionStart(session *proxy.Session, serverName string
 
 	w.mapUI = NewMapUI(w)
 
-	w.scripting = nil
-	if w.settings.Script != "" {
-		w.scripting = scripting.New()
+	// the script handler runs first, so its vm is there if a script is used
+	w.scripting = scripting.ForSession(session)
+	if w.scripting != nil {
 		w.scripting.GetWorld = func() *worldstate.World {
 			return w.worldState // locked by calls to the vm
 		}
-		w.scripting.DisplayChatMessage = func(msg string) {
-			w.session.SendMessage(msg)
-		}
 		w.scripting.SetIngameMap = func(enabled bool) {
 			w.mapUI.SetEnabled(enabled)
 		}
-		err := w.scripting.Load(w.settings.Script)
-		if err != nil {
-			return err
-		}
 	}
 
+	w.addCommands(session)
+
+	// initialize a worldstate
+	worldState, err := worldstate.New(w.ctx, w.serverState.dimensions, w.mapUI.SetChunk)
+	if err != nil {
+		return err
+	}
+	worldState.VoidGen = w.settings.VoidGen
+	worldState.FlushInterval = w.settings.FlushInterval
+	worldState.RecordHistory = w.settings.History
+	w.worldState = worldState
+	return nil
+}
+
+func (w *worldsHandler) addCommands(session *proxy.Session) {
 	session.AddCommand(func(cmdline []string) bool {
 		return w.setWorldName(strings.Join(cmdline, " "))
 	}, protocol.Command{
@@ -216,17 +304,15 @@ func (w *worldsHandler) onSessionStart(session *proxy.Session, serverName string
 		Description: "immediately save and reset the world state",
 	})
 
-	// initialize a worldstate
-	worldState, err := worldstate.New(w.ctx, w.serverState.dimensions, w.mapUI.SetChunk)
-	if err != nil {
-		return err
-	}
-	worldState.VoidGen = w.settings.VoidGen
-	w.worldState = worldState
-	return nil
+	w.addAreaCommand(session)
+	w.addStructureCommand(session)
+	w.addCoverageCommand(session)
 }
 
-func (w *worldsHandler) onConnect(_ *proxy.Session) bool {
+func (w *worldsHandler) onConnect(session *proxy.Session) bool {
+	resumed := w.session != session
+	w.session = session
+
 	messages.SendEvent(&messages.EventSetUIState{
 		State: messages.UIStateMain,
 	})
@@ -250,6 +336,17 @@ func (w *worldsHandler) onConnect(_ *proxy.Session) bool {
 		mapItem.StackNetworkID = 0xffff + rand.Int31n(0xfff)
 	}
 
+	if w.settings.FillHoles {
+		w.startFillHoles()
+	}
+	if w.settings.Coverage {
+		w.startCoverage()
+	}
+
+	if resumed {
+		w.mapUI.Resume(w.ctx)
+		return false
+	}
 	w.session.SendMessage(locale.Loc("use_setname", nil))
 	w.mapUI.Start(w.ctx)
 	return false
@@ -312,7 +409,7 @@ func (w *worldsHandler) SaveAndReset(end bool, dim world.Dimension) {
 	worldState := w.worldState
 	w.worldState = nil
 
-	if len(worldState.StoredChunks) > 0 {
+	if worldState.ChunkCount() > 0 {
 		// save image of the map
 		if w.settings.SaveImage {
 			f, _ := os.Create(worldState.Folder + ".png")
@@ -340,14 +437,25 @@ func (w *worldsHandler) SaveAndReset(end bool, dim world.Dimension) {
 			w.log.Error(err)
 		}
 		worldState.VoidGen = w.settings.VoidGen
+		worldState.FlushInterval = w.settings.FlushInterval
+		worldState.RecordHistory = w.settings.History
 		worldState.SetDimension(dim)
 		w.worldState = worldState
 		w.openWorldState()
 	}
 }
 
+// changeDimension continues the current world in another dimension instead of saving it
+func (w *worldsHandler) changeDimension(dim world.Dimension) {
+	w.mapUI.Reset()
+	w.currentWorld(func(world *worldstate.World) {
+		world.ChangeDimension(dim, w.settings.ExcludedMobs, w.settings.EntityCulling, w.serverState.getEntityRenderDistance())
+	})
+	w.log.Infof("Continuing %s in the %v", w.worldState.Name, dim)
+}
+
 func (w *worldsHandler) saveWorldState(worldState *worldstate.World, player proxy.Player, behaviorPack *behaviourpack.Pack) error {
-	text := locale.Loc("saving_world", locale.Strmap{"Name": worldState.Name, "Count": len(worldState.StoredChunks)})
+	text := locale.Loc("saving_world", locale.Strmap{"Name": worldState.Name, "Count": worldState.ChunkCount()})
 	w.log.Info(text)
 	w.session.SendMessage(text)
 
@@ -356,6 +464,10 @@ func (w *worldsHandler) saveWorldState(worldState *worldstate.World, player prox
 		State:     "Saving",
 	})
 
+	if w.scripting != nil {
+		w.scripting.OnBeforeSave(worldState)
+	}
+
 	var playerSkins = make(map[uuid.UUID]*protocol.Skin)
 	maps.Copy(playerSkins, w.serverState.playerSkins)
 
@@ -372,41 +484,61 @@ func (w *worldsHandler) saveWorldState(worldState *worldstate.World, player prox
 		return err
 	}
 
+	if w.settings.Java {
+		messages.SendEvent(&messages.EventProcessingWorldUpdate{
+			WorldName: worldState.Name,
+			State:     "Converting to java",
+		})
+		if err := anvil.ConvertWorld(worldState.Folder, worldState.Folder+"-java"); err != nil {
+			w.log.WithField("world", worldState.Name).Errorf("java export: %s", err)
+		}
+	}
+
 	messages.SendEvent(&messages.EventProcessingWorldUpdate{
 		WorldName: worldState.Name,
 		State:     "Writing mcworld file",
 	})
 
-	filename := worldState.Folder + ".mcworld"
-	f, err := os.Create(filename)
+	filename, err := zipWorld(worldState.Folder)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	zw := zip.NewWriter(f)
-	utils.ZipCompressPool(zw)
-	err = zw.AddFS(os.DirFS(worldState.Folder))
-	if err != nil {
-		return err
-	}
-	err = zw.Close()
-	if err != nil {
-		return err
+	if err := w.writeReport(worldState, reportFilename(worldState.Folder)); err != nil {
+		w.log.WithError(err).Warn("Failed to write world report")
 	}
 	total, active := worldState.EntityCounts(w.serverState.getEntityRenderDistance())
 	w.log.WithFields(logrus.Fields{
 		"Entities": fmt.Sprintf("%d (%d)", total, active),
-		"Chunks":   len(worldState.StoredChunks),
+		"Chunks":   worldState.ChunkCount(),
 	}).Info(locale.Loc("saved", locale.Strmap{"Name": filename}))
 	messages.SendEvent(&messages.EventFinishedSavingWorld{
 		WorldName: worldState.Name,
 		Filepath:  filename,
-		Chunks:    len(worldState.StoredChunks),
+		Chunks:    worldState.ChunkCount(),
 		Entities:  total,
 	})
 	return nil
 }
 
+// zipWorld writes the world folder to a .mcworld file next to it
+func zipWorld(folder string) (string, error) {
+	filename := folder + ".mcworld"
+	f, err := os.Create(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	utils.ZipCompressPool(zw)
+	if err := zw.AddFS(os.DirFS(folder)); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return filename, f.Close()
+}
+
 func (w *worldsHandler) defaultWorldName() string {
 	worldName := "world"
 	if w.serverState.worldCounter > 0 {
@@ -426,6 +558,16 @@ func (w *worldsHandler) openWorldState() {
 	w.worldState.BlockRegistry = w.serverState.blocks
 	w.worldState.ResourcePacks = w.session.Server.ResourcePacks()
 	w.worldState.UseHashedRids = w.serverState.useHashedRids
+	w.worldState.SetAreas(w.areas)
+	if w.settings.Resume != "" && w.serverState.worldCounter == 0 {
+		resumeFolder := utils.PathData(w.settings.Resume)
+		err := w.worldState.OpenExisting(resumeFolder, w.settings.MergePolicy)
+		if err == nil {
+			w.log.Infof("Adding to %s", resumeFolder)
+			return
+		}
+		w.log.WithError(err).Errorf("Cant resume %s, starting a new world", resumeFolder)
+	}
 	w.worldState.Open(w.defaultWorldName(), folder)
 }
 
@@ -0,0 +1,84 @@
+package worldstate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/df-mc/dragonfly/server/world"
+)
+
+// Area is a rectangle of chunks in one dimension that is kept when downloading
+type Area struct {
+	Dimension int
+	Min, Max  world.ChunkPos
+}
+
+// NewArea makes an area from two block corners
+func NewArea(dimension int, x1, z1, x2, z2 int32) Area {
+	return Area{
+		Dimension: dimension,
+		Min:       world.ChunkPos{min(x1, x2) >> 4, min(z1, z2) >> 4},
+		Max:       world.ChunkPos{max(x1, x2) >> 4, max(z1, z2) >> 4},
+	}
+}
+
+func (a Area) Contains(dimension int, pos world.ChunkPos) bool {
+	return a.Dimension == dimension &&
+		pos[0] >= a.Min[0] && pos[0] <= a.Max[0] &&
+		pos[1] >= a.Min[1] && pos[1] <= a.Max[1]
+}
+
+func (a Area) String() string {
+	dim, _ := world.DimensionByID(a.Dimension)
+	return fmt.Sprintf("%v: %d %d to %d %d", dim, a.Min[0]<<4, a.Min[1]<<4, a.Max[0]<<4+15, a.Max[1]<<4+15)
+}
+
+var dimensionNames = map[string]int{
+	"overworld": 0,
+	"nether":    1,
+	"end":       2,
+}
+
+// ParseAreas reads areas seperated by ";" in block coordinates,
+// each is "x1,z1,x2,z2" for a rectangle or "x,z,radius" for a square around a point,
+// optionally starting with the dimension like "nether:0,0,200"
+func ParseAreas(s string) ([]Area, error) {
+	var areas []Area
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		dimension := 0
+		if name, coords, ok := strings.Cut(part, ":"); ok {
+			id, ok := dimensionNames[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown dimension %s", name)
+			}
+			dimension = id
+			part = coords
+		}
+
+		var values []int32
+		for _, v := range strings.Split(part, ",") {
+			i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("area %s: %w", part, err)
+			}
+			values = append(values, int32(i))
+		}
+
+		switch len(values) {
+		case 3:
+			x, z, radius := values[0], values[1], values[2]
+			areas = append(areas, NewArea(dimension, x-radius, z-radius, x+radius, z+radius))
+		case 4:
+			areas = append(areas, NewArea(dimension, values[0], values[1], values[2], values[3]))
+		default:
+			return nil, fmt.Errorf("area %s has to be x1,z1,x2,z2 or x,z,radius", part)
+		}
+	}
+	return areas, nil
+}
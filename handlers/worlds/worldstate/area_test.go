@@ -0,0 +1,40 @@
+package worldstate
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/df-mc/dragonfly/server/world"
+)
+
+func TestParseAreas(t *testing.T) {
+	type test struct {
+		value    string
+		expected []Area
+	}
+	var tests = []test{
+		{value: "", expected: nil},
+		{value: "0,0,31,47", expected: []Area{{Dimension: 0, Min: world.ChunkPos{0, 0}, Max: world.ChunkPos{1, 2}}}},
+		{value: "100,-100,16", expected: []Area{{Dimension: 0, Min: world.ChunkPos{5, -8}, Max: world.ChunkPos{7, -6}}}},
+		{value: "nether:-1,-1,1,1; end:0,0,0", expected: []Area{
+			{Dimension: 1, Min: world.ChunkPos{-1, -1}, Max: world.ChunkPos{0, 0}},
+			{Dimension: 2, Min: world.ChunkPos{0, 0}, Max: world.ChunkPos{0, 0}},
+		}},
+	}
+
+	for _, tt := range tests {
+		areas, err := ParseAreas(tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(areas, tt.expected) {
+			t.Fatalf("%s expected: %v\ngot: %v\n", tt.value, tt.expected, areas)
+		}
+	}
+
+	for _, invalid := range []string{"1,2", "moon:0,0,1", "a,b,c,d"} {
+		if _, err := ParseAreas(invalid); err == nil {
+			t.Fatalf("%s should not parse", invalid)
+		}
+	}
+}
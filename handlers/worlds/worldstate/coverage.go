@@ -0,0 +1,64 @@
+package worldstate
+
+import (
+	"slices"
+
+	"github.com/df-mc/dragonfly/server/world"
+)
+
+// at most this many chunks are checked, so huge areas dont stall the caller
+const maxCoverageChunks = 1 << 20
+
+// Coverage is how much of the target areas has been received
+type Coverage struct {
+	Received, Total int
+	// the missing chunk closest to where the search started from
+	Nearest    world.ChunkPos
+	HasMissing bool
+}
+
+// Coverage checks which chunks of the areas in the current dimension are missing,
+// chunks that were only air count as received
+func (w *World) Coverage(areas []Area, from world.ChunkPos) Coverage {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	dimension, _ := world.DimensionID(w.dimension)
+	return chunkCoverage(areas, dimension, from, func(pos world.ChunkPos) bool {
+		if _, ok := w.StoredChunks[pos]; ok {
+			return true
+		}
+		_, ok := w.emptyChunks[pos]
+		return ok
+	})
+}
+
+func chunkCoverage(areas []Area, dimension int, from world.ChunkPos, received func(pos world.ChunkPos) bool) (c Coverage) {
+	var nearestDist int64
+	for i, area := range areas {
+		if area.Dimension != dimension {
+			continue
+		}
+		for x := area.Min[0]; x <= area.Max[0]; x++ {
+			for z := area.Min[1]; z <= area.Max[1]; z++ {
+				if c.Total >= maxCoverageChunks {
+					return c
+				}
+				pos := world.ChunkPos{x, z}
+				// counted by an earlier area already
+				if slices.ContainsFunc(areas[:i], func(a Area) bool { return a.Contains(dimension, pos) }) {
+					continue
+				}
+				c.Total++
+				if received(pos) {
+					c.Received++
+					continue
+				}
+				dx, dz := int64(x-from[0]), int64(z-from[1])
+				if dist := dx*dx + dz*dz; !c.HasMissing || dist < nearestDist {
+					c.Nearest, c.HasMissing, nearestDist = pos, true, dist
+				}
+			}
+		}
+	}
+	return c
+}
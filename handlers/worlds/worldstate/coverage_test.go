@@ -0,0 +1,30 @@
+package worldstate
+
+import (
+	"testing"
+
+	"github.com/df-mc/dragonfly/server/world"
+)
+
+func TestChunkCoverage(t *testing.T) {
+	areas := []Area{
+		{Dimension: 0, Min: world.ChunkPos{0, 0}, Max: world.ChunkPos{3, 3}},
+		{Dimension: 0, Min: world.ChunkPos{2, 2}, Max: world.ChunkPos{5, 3}},
+		{Dimension: 1, Min: world.ChunkPos{0, 0}, Max: world.ChunkPos{9, 9}},
+	}
+	received := func(pos world.ChunkPos) bool {
+		return pos[0] < 3
+	}
+	c := chunkCoverage(areas, 0, world.ChunkPos{0, 3}, received)
+	if c.Total != 20 || c.Received != 12 {
+		t.Fatalf("expected 12/20 received, got %d/%d", c.Received, c.Total)
+	}
+	if !c.HasMissing || c.Nearest != (world.ChunkPos{3, 3}) {
+		t.Fatalf("expected nearest missing 3,3, got %v", c.Nearest)
+	}
+
+	c = chunkCoverage(areas, 0, world.ChunkPos{}, func(world.ChunkPos) bool { return true })
+	if c.HasMissing || c.Received != c.Total {
+		t.Fatalf("expected full coverage, got %+v", c)
+	}
+}
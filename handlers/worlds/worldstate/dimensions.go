@@ -0,0 +1,69 @@
+package worldstate
+
+import (
+	"time"
+
+	"github.com/df-mc/dragonfly/server/world"
+)
+
+// DimensionIDByName returns the id of a vanilla dimension from its name in DimensionData
+func DimensionIDByName(name string) (int, bool) {
+	switch name {
+	case "minecraft:overworld":
+		return 0, true
+	case "minecraft:nether":
+		return 1, true
+	case "minecraft:the_end":
+		return 2, true
+	}
+	return 0, false
+}
+
+// ChunkCount is how many chunks were downloaded in all dimensions of this world,
+// chunks received again on a later visit to a dimension count again
+func (w *World) ChunkCount() int {
+	return len(w.StoredChunks) + w.otherDimensionChunks
+}
+
+// ChangeDimension writes what was downloaded in the current dimension and continues in dim,
+// going back to a dimension adds to the chunks it has already.
+// the entities that are written are filtered like when saving, they are gone from memory after
+func (w *World) ChangeDimension(dim world.Dimension, excludedMobs []string, entityCulling bool, entityRenderDistance float32) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	if dim == w.dimension {
+		return
+	}
+
+	w.applyBlockUpdates()
+	if err := w.storeMemToProvider(); err != nil {
+		w.log.WithError(err).Error("Failed to store chunks")
+	}
+	if w.provider != nil {
+		w.storeEntities(w.groupEntities(excludedMobs, entityCulling, entityRenderDistance))
+		if err := w.writeHistory(); err != nil {
+			w.log.WithError(err).Warn("Failed to write block history")
+		}
+	}
+
+	// chunks and entities are per dimension, the server sends everything again for the new one
+	w.otherDimensionChunks += len(w.StoredChunks)
+	clear(w.StoredChunks)
+	clear(w.emptyChunks)
+	clear(w.memState.chunks)
+	clear(w.memState.entities)
+	clear(w.memState.entityLinks)
+	clear(w.memState.uniqueIDsToRuntimeIDs)
+	clear(w.flushedEntities)
+	clear(w.savedChunks)
+	w.blockUpdatesLock.Lock()
+	clear(w.blockUpdates)
+	w.blockUpdatesLock.Unlock()
+
+	w.SetDimension(dim)
+}
+
+// Started is when this world was started
+func (w *World) Started() time.Time {
+	return w.started
+}
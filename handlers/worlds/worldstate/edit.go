@@ -0,0 +1,239 @@
+package worldstate
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/entity"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+)
+
+// ErrNoChunk is returned when editing a chunk that was not downloaded
+var ErrNoChunk = errors.New("chunk not downloaded")
+
+// the most blocks one fill can change
+const maxFillVolume = 1 << 24
+
+func checkLayer(layer uint8) error {
+	if layer > 1 {
+		return fmt.Errorf("layer %d, blocks only have layer 0 and 1", layer)
+	}
+	return nil
+}
+
+func (w *World) blockRuntimeID(name string, properties map[string]any) (uint32, error) {
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	rid, ok := w.BlockRegistry.StateToRuntimeID(name, properties)
+	if !ok {
+		return 0, fmt.Errorf("unknown block %s %v", name, properties)
+	}
+	return rid, nil
+}
+
+// Block returns the block at pos in the current dimension
+func (w *World) Block(pos cube.Pos, layer uint8) (name string, properties map[string]any, err error) {
+	if err := checkLayer(layer); err != nil {
+		return "", nil, err
+	}
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	w.applyBlockUpdates()
+	if pos.OutOfBounds(w.dimRange) {
+		return "", nil, fmt.Errorf("y %d is outside of the world", pos[1])
+	}
+	chunkPos, _ := cubePosInChunk(pos)
+	ch, ok, err := w.loadChunkLocked(chunkPos)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		return "", nil, ErrNoChunk
+	}
+	name, properties, _ = w.BlockRegistry.RuntimeIDToState(ch.Block(uint8(pos[0]&15), int16(pos[1]), uint8(pos[2]&15), layer))
+	return name, properties, nil
+}
+
+// SetBlock places a block, the block entity there is removed if the block changes
+func (w *World) SetBlock(pos cube.Pos, layer uint8, name string, properties map[string]any) error {
+	if err := checkLayer(layer); err != nil {
+		return err
+	}
+	rid, err := w.blockRuntimeID(name, properties)
+	if err != nil {
+		return err
+	}
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	w.applyBlockUpdates()
+	if pos.OutOfBounds(w.dimRange) {
+		return fmt.Errorf("y %d is outside of the world", pos[1])
+	}
+	chunkPos, _ := cubePosInChunk(pos)
+	ch, ok, err := w.loadChunkLocked(chunkPos)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNoChunk
+	}
+	x, y, z := uint8(pos[0]&15), int16(pos[1]), uint8(pos[2]&15)
+	if ch.Block(x, y, z, layer) != rid {
+		ch.SetBlock(x, y, z, layer, rid)
+		if layer == 0 {
+			delete(ch.BlockEntities, pos)
+		}
+	}
+	return w.storeChunkLocked(chunkPos, ch)
+}
+
+// Fill sets every block between a and b in downloaded chunks, only blocks named replace if it is set
+func (w *World) Fill(a, b cube.Pos, name string, properties map[string]any, replace string) (changed int, err error) {
+	rid, err := w.blockRuntimeID(name, properties)
+	if err != nil {
+		return 0, err
+	}
+	lo := cube.Pos{min(a[0], b[0]), min(a[1], b[1]), min(a[2], b[2])}
+	hi := cube.Pos{max(a[0], b[0]), max(a[1], b[1]), max(a[2], b[2])}
+	// checked per axis so the volume cant overflow
+	volume := 1
+	for i := range 3 {
+		size := hi[i] - lo[i] + 1
+		if size < 1 || size > maxFillVolume {
+			return 0, fmt.Errorf("fill is longer than %d blocks", maxFillVolume)
+		}
+		volume *= size
+		if volume > maxFillVolume {
+			return 0, fmt.Errorf("fill is bigger than %d blocks", maxFillVolume)
+		}
+	}
+
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	w.applyBlockUpdates()
+	lo[1] = max(lo[1], w.dimRange[0])
+	hi[1] = min(hi[1], w.dimRange[1])
+
+	for cx := lo[0] >> 4; cx <= hi[0]>>4; cx++ {
+		for cz := lo[2] >> 4; cz <= hi[2]>>4; cz++ {
+			chunkPos := world.ChunkPos{int32(cx), int32(cz)}
+			ch, ok, err := w.loadChunkLocked(chunkPos)
+			if err != nil {
+				return changed, err
+			}
+			if !ok {
+				continue
+			}
+			var chunkChanged bool
+			for x := max(lo[0], cx<<4); x <= min(hi[0], cx<<4+15); x++ {
+				for z := max(lo[2], cz<<4); z <= min(hi[2], cz<<4+15); z++ {
+					for y := lo[1]; y <= hi[1]; y++ {
+						bx, by, bz := uint8(x&15), int16(y), uint8(z&15)
+						current := ch.Block(bx, by, bz, 0)
+						if current == rid {
+							continue
+						}
+						if replace != "" {
+							currentName, _, _ := w.BlockRegistry.RuntimeIDToState(current)
+							if currentName != replace {
+								continue
+							}
+						}
+						ch.SetBlock(bx, by, bz, 0, rid)
+						delete(ch.BlockEntities, cube.Pos{x, y, z})
+						chunkChanged = true
+						changed++
+					}
+				}
+			}
+			if chunkChanged {
+				if err := w.storeChunkLocked(chunkPos, ch); err != nil {
+					return changed, err
+				}
+			}
+		}
+	}
+	return changed, nil
+}
+
+// BlockEntity returns the nbt of the block entity at pos, nil if there is none
+func (w *World) BlockEntity(pos cube.Pos) (map[string]any, error) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	chunkPos, _ := cubePosInChunk(pos)
+	ch, ok, err := w.loadChunkLocked(chunkPos)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNoChunk
+	}
+	return ch.BlockEntities[pos], nil
+}
+
+// RemoveBlockEntity removes the block entity at pos, the block stays
+func (w *World) RemoveBlockEntity(pos cube.Pos) error {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	chunkPos, _ := cubePosInChunk(pos)
+	ch, ok, err := w.loadChunkLocked(chunkPos)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNoChunk
+	}
+	if _, ok := ch.BlockEntities[pos]; !ok {
+		return nil
+	}
+	delete(ch.BlockEntities, pos)
+	return w.storeChunkLocked(chunkPos, ch)
+}
+
+// Entities returns copies of the entities of the current dimension that will be saved, sorted by runtime id,
+// changes to them are stored with SetEntity
+func (w *World) Entities() []*entity.Entity {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	entities := make([]*entity.Entity, 0, len(w.memState.entities))
+	for _, ent := range w.memState.entities {
+		entities = append(entities, ent.Clone())
+	}
+	slices.SortFunc(entities, func(a, b *entity.Entity) int {
+		return cmp.Compare(a.RuntimeID, b.RuntimeID)
+	})
+	return entities
+}
+
+// SetEntity stores a changed copy from Entities over the entity with its runtime id,
+// the ids can't be changed. returns if the entity still exists
+func (w *World) SetEntity(ent *entity.Entity) bool {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	existing, ok := w.memState.entities[ent.RuntimeID]
+	if !ok {
+		return false
+	}
+	uniqueID := existing.UniqueID
+	*existing = *ent.Clone()
+	existing.UniqueID = uniqueID
+	return true
+}
+
+// RemoveEntity removes an entity so it is not saved, returns if it existed
+func (w *World) RemoveEntity(id entity.RuntimeID) bool {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	ent, ok := w.memState.entities[id]
+	if !ok {
+		return false
+	}
+	delete(w.memState.entities, id)
+	delete(w.memState.uniqueIDsToRuntimeIDs, ent.UniqueID)
+	delete(w.memState.entityLinks, ent.UniqueID)
+	return true
+}
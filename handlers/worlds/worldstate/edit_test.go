@@ -0,0 +1,29 @@
+package worldstate
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+)
+
+func TestFillTooBig(t *testing.T) {
+	w, err := New(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.BlockRegistry = world.DefaultBlockRegistry
+	for _, area := range [][2]cube.Pos{
+		{{0, 0, 0}, {1 << 12, 1 << 12, 1}},
+		// each axis is small enough but the volume overflows
+		{{0, 0, 0}, {1 << 22, 1 << 22, 1 << 22}},
+		// the length of the axis overflows
+		{{math.MinInt, 0, 0}, {math.MaxInt, 0, 0}},
+	} {
+		if _, err := w.Fill(area[0], area[1], "minecraft:stone", nil, ""); err == nil {
+			t.Fatalf("fill from %v to %v didnt fail", area[0], area[1])
+		}
+	}
+}
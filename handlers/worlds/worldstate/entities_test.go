@@ -0,0 +1,47 @@
+package worldstate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/entity"
+)
+
+func TestDetachEntities(t *testing.T) {
+	w, err := New(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, uniqueID := range map[entity.RuntimeID]entity.UniqueID{5: 42, 6: 43} {
+		w.ActAddedEntity(id, uniqueID, func(ent *entity.Entity) error {
+			ent.UniqueID = uniqueID
+			ent.EntityType = "minecraft:pig"
+			return nil
+		})
+	}
+
+	w.DetachEntities()
+	if w.memState.GetEntity(5) != nil {
+		t.Fatal("runtime id of the old connection still used")
+	}
+
+	// the new connection reuses 6 for another entity and adds 42 as 9
+	var adopted, fresh *entity.Entity
+	w.ActAddedEntity(6, 100, func(ent *entity.Entity) error {
+		fresh = ent
+		return nil
+	})
+	w.ActAddedEntity(9, 42, func(ent *entity.Entity) error {
+		adopted = ent
+		return nil
+	})
+	if fresh.EntityType != "" {
+		t.Fatal("new entity was merged into a detached one")
+	}
+	if adopted.EntityType != "minecraft:pig" || adopted.RuntimeID != 9 {
+		t.Fatalf("entity 42 was not taken over: %+v", adopted)
+	}
+	if len(w.memState.entities) != 3 {
+		t.Fatalf("expected 3 entities, got %d", len(w.memState.entities))
+	}
+}
@@ -0,0 +1,206 @@
+package worldstate
+
+import (
+	"bufio"
+	"cmp"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/merge"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/df-mc/dragonfly/server/world/mcdb"
+	"github.com/df-mc/goleveldb/leveldb"
+	"github.com/df-mc/goleveldb/leveldb/opt"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+)
+
+// historyExt is added to the world folder for the file block changes are recorded in
+const historyExt = ".history"
+
+// BlockChange is one block update received from the server
+type BlockChange struct {
+	// unix milliseconds of when the update was received
+	Time      int64  `nbt:"time"`
+	Dimension int32  `nbt:"dimension"`
+	X         int32  `nbt:"x"`
+	Y         int32  `nbt:"y"`
+	Z         int32  `nbt:"z"`
+	Layer     uint8  `nbt:"layer"`
+	Name      string `nbt:"name"`
+	// the block before the update
+	PrevName       string         `nbt:"prev_name"`
+	Properties     map[string]any `nbt:"properties"`
+	PrevProperties map[string]any `nbt:"prev_properties"`
+}
+
+func (c BlockChange) ChunkPos() world.ChunkPos {
+	return world.ChunkPos{c.X >> 4, c.Z >> 4}
+}
+
+func (c BlockChange) String() string {
+	dim, _ := world.DimensionByID(int(c.Dimension))
+	return fmt.Sprintf("%s %v %d %d %d: %s %v -> %s %v",
+		time.UnixMilli(c.Time).Format(time.DateTime), dim, c.X, c.Y, c.Z,
+		c.PrevName, c.PrevProperties, c.Name, c.Properties,
+	)
+}
+
+func (w *World) recordChange(update blockUpdate, prevRid uint32) {
+	name, properties, _ := w.BlockRegistry.RuntimeIDToState(update.rid)
+	prevName, prevProperties, _ := w.BlockRegistry.RuntimeIDToState(prevRid)
+	if name == prevName && fmt.Sprint(properties) == fmt.Sprint(prevProperties) {
+		return
+	}
+	dimension, _ := world.DimensionID(w.dimension)
+	w.history = append(w.history, BlockChange{
+		Time:           update.time.UnixMilli(),
+		Dimension:      int32(dimension),
+		X:              update.pos.X(),
+		Y:              update.pos.Y(),
+		Z:              update.pos.Z(),
+		Layer:          update.layer,
+		Name:           name,
+		Properties:     properties,
+		PrevName:       prevName,
+		PrevProperties: prevProperties,
+	})
+}
+
+// writeHistory appends the recorded changes to the history file as one more gzip member
+func (w *World) writeHistory() error {
+	if len(w.history) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(w.Folder+historyExt, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	enc := nbt.NewEncoderWithEncoding(zw, nbt.LittleEndian)
+	for _, change := range w.history {
+		if err := enc.Encode(change); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	w.history = w.history[:0]
+	return f.Close()
+}
+
+// ReadHistory reads the block changes recorded for the world in folder, oldest first
+func ReadHistory(folder string) ([]BlockChange, error) {
+	f, err := os.Open(folder + historyExt)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(zr)
+	dec := nbt.NewDecoderWithEncoding(br, nbt.LittleEndian)
+	var changes []BlockChange
+	for {
+		// the decoder doesnt return io.EOF at the end
+		if _, err := br.Peek(1); errors.Is(err, io.EOF) {
+			break
+		}
+		var change BlockChange
+		if err := dec.Decode(&change); err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+	slices.SortStableFunc(changes, func(a, b BlockChange) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+	return changes, nil
+}
+
+type historyChunk struct {
+	dimension world.Dimension
+	pos       world.ChunkPos
+}
+
+// Rewind writes a copy of the world in folder to outFolder as it was at the time given,
+// by undoing the changes after it. only block updates are known, chunks the server sent again are not
+func Rewind(folder, outFolder string, changes []BlockChange, at time.Time) (undone int, err error) {
+	if err := os.RemoveAll(outFolder); err != nil {
+		return 0, err
+	}
+	if err := os.CopyFS(outFolder, os.DirFS(folder)); err != nil {
+		return 0, err
+	}
+
+	blockReg := &merge.BlockRegistry{
+		BlockRegistry: world.DefaultBlockRegistry,
+		Rids:          make(map[uint32]merge.Block),
+	}
+	db, err := mcdb.Config{
+		Log: slog.Default(),
+		LDBOptions: &opt.Options{
+			Compression: opt.DefaultCompression,
+		},
+		Blocks: blockReg,
+	}.Open(outFolder)
+	if err != nil {
+		return 0, err
+	}
+	undone, err = undoChanges(db, blockReg, changes, at)
+	if err != nil {
+		db.Close()
+		return undone, err
+	}
+	s := db.Settings()
+	s.Name += " " + at.Format(time.DateTime)
+	db.SaveSettings(s)
+	return undone, db.Close()
+}
+
+// undoChanges sets the blocks changed after at back to what they were, newest first
+func undoChanges(db *mcdb.DB, blockReg world.BlockRegistry, changes []BlockChange, at time.Time) (undone int, err error) {
+	columns := make(map[historyChunk]*chunk.Column)
+	atMilli := at.UnixMilli()
+	for _, change := range slices.Backward(changes) {
+		if change.Time <= atMilli {
+			break
+		}
+		dim, ok := world.DimensionByID(int(change.Dimension))
+		if !ok {
+			continue
+		}
+		key := historyChunk{dim, change.ChunkPos()}
+		col, ok := columns[key]
+		if !ok {
+			col, err = db.LoadColumn(key.pos, dim)
+			if errors.Is(err, leveldb.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return undone, err
+			}
+			columns[key] = col
+		}
+		rid, _ := blockReg.StateToRuntimeID(change.PrevName, change.PrevProperties)
+		col.Chunk.SetBlock(uint8(change.X&15), int16(change.Y), uint8(change.Z&15), change.Layer, rid)
+		undone++
+	}
+
+	for key, col := range columns {
+		if err := db.StoreColumn(key.pos, key.dimension, col); err != nil {
+			return undone, err
+		}
+	}
+	return undone, nil
+}
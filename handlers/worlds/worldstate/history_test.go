@@ -0,0 +1,101 @@
+package worldstate
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/df-mc/dragonfly/server/world/mcdb"
+)
+
+func TestReadHistory(t *testing.T) {
+	w, err := New(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Folder = filepath.Join(t.TempDir(), "world")
+
+	// every flush appends a gzip member, the changes come back sorted by time
+	w.history = []BlockChange{
+		{Time: 3, X: -1, Y: 64, Z: 17, Name: "minecraft:stone", Properties: map[string]any{}},
+		{Time: 1, Name: "minecraft:dirt", Properties: map[string]any{}},
+	}
+	if err := w.writeHistory(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.history) != 0 {
+		t.Fatal("written changes were kept")
+	}
+	w.history = []BlockChange{{Time: 2, Layer: 1, Name: "minecraft:water", Properties: map[string]any{"liquid_depth": int32(0)}}}
+	if err := w.writeHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := ReadHistory(w.Folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(changes))
+	}
+	for i, change := range changes {
+		if change.Time != int64(i+1) {
+			t.Fatalf("change %d has time %d", i, change.Time)
+		}
+	}
+	if c := changes[2]; c.X != -1 || c.Z != 17 || c.Name != "minecraft:stone" || c.ChunkPos() != (world.ChunkPos{-1, 1}) {
+		t.Fatalf("change didnt round trip, got %v", c)
+	}
+	if c := changes[1]; c.Layer != 1 || c.Properties["liquid_depth"] != int32(0) {
+		t.Fatalf("change didnt round trip, got %v", c)
+	}
+}
+
+func TestUndoChanges(t *testing.T) {
+	blocks := world.DefaultBlockRegistry
+	rid := func(name string) uint32 {
+		rid, ok := blocks.StateToRuntimeID(name, map[string]any{})
+		if !ok {
+			t.Fatalf("no runtime id for %s", name)
+		}
+		return rid
+	}
+	db, err := mcdb.Config{Log: slog.Default(), Blocks: blocks}.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	pos := world.ChunkPos{0, 0}
+	ch := chunk.New(rid("minecraft:air"), world.Overworld.Range())
+	ch.SetBlock(1, 64, 1, 0, rid("minecraft:gold_block"))
+	if err := db.StoreColumn(pos, world.Overworld, &chunk.Column{Chunk: ch}); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := []BlockChange{
+		{Time: 1000, X: 1, Y: 64, Z: 1, Name: "minecraft:stone", PrevName: "minecraft:air", PrevProperties: map[string]any{}},
+		{Time: 3000, X: 1, Y: 64, Z: 1, Name: "minecraft:gold_block", PrevName: "minecraft:stone", PrevProperties: map[string]any{}},
+		// chunks that arent in the world are skipped
+		{Time: 4000, X: 160, Y: 64, Z: 160, Name: "minecraft:stone", PrevName: "minecraft:air", PrevProperties: map[string]any{}},
+	}
+	undone, err := undoChanges(db, blocks, changes, time.UnixMilli(2000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undone != 1 {
+		t.Fatalf("expected 1 change undone, got %d", undone)
+	}
+	col, err := db.LoadColumn(pos, world.Overworld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := col.Chunk.Block(1, 64, 1, 0); got != rid("minecraft:stone") {
+		name, _, _ := blocks.RuntimeIDToState(got)
+		t.Fatalf("expected stone at the time, got %s", name)
+	}
+}
@@ -0,0 +1,154 @@
+package worldstate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/mcdb"
+	"github.com/df-mc/goleveldb/leveldb/opt"
+)
+
+// journalName is written into a world folder while it is being downloaded and removed once it is saved,
+// a folder that still has one after bedrocktool exited was not saved
+const journalName = "bedrocktool_journal.json"
+
+// DefaultFlushInterval is how often downloaded chunks and entities are written to disk
+const DefaultFlushInterval = 10 * time.Second
+
+// journal has what is needed to finish the level.dat of a world that wasnt saved
+type journal struct {
+	Name      string    `json:"name"`
+	Dimension int       `json:"dimension"`
+	VoidGen   bool      `json:"void_gen"`
+	Spawn     cube.Pos  `json:"spawn"`
+	Time      int64     `json:"time"`
+	Chunks    int       `json:"chunks"`
+	Flushed   time.Time `json:"flushed"`
+	// process that is downloading the world, its folder is left alone while it runs
+	PID int `json:"pid"`
+}
+
+// flushLocked writes everything downloaded so far to the world folder so a crash doesnt lose it
+func (w *World) flushLocked() {
+	// the world was saved while this waited for the lock, the provider is closed already
+	if w.ctx.Err() != nil {
+		return
+	}
+	w.applyBlockUpdates()
+	if err := w.storeMemToProvider(); err != nil {
+		w.log.WithError(err).Error("Failed to flush chunks")
+		return
+	}
+	if w.provider == nil {
+		return
+	}
+	w.storeEntities(w.groupEntities(nil, false, 0))
+	if err := w.writeHistory(); err != nil {
+		w.log.WithError(err).Warn("Failed to write block history")
+	}
+	if err := w.writeJournal(); err != nil {
+		w.log.WithError(err).Warn("Failed to write journal")
+	}
+}
+
+func (w *World) writeJournal() error {
+	dimension, _ := world.DimensionID(w.dimension)
+	data, err := json.Marshal(journal{
+		Name:      w.Name,
+		Dimension: dimension,
+		VoidGen:   w.VoidGen,
+		Spawn:     cube.Pos{int(w.playerPos.X()), int(w.playerPos.Y()), int(w.playerPos.Z())},
+		Time:      int64(w.time) + int64(time.Since(w.timeSync)/time.Millisecond)/50,
+		Chunks:    w.ChunkCount(),
+		Flushed:   time.Now(),
+		PID:       os.Getpid(),
+	})
+	if err != nil {
+		return err
+	}
+	// write then rename so a crash while writing leaves the old journal
+	tmp := filepath.Join(w.Folder, journalName+".tmp")
+	if err := os.WriteFile(tmp, data, 0o666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(w.Folder, journalName))
+}
+
+func (w *World) removeJournal() {
+	err := os.Remove(filepath.Join(w.Folder, journalName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		w.log.WithError(err).Warn("Failed to remove journal")
+	}
+}
+
+// HasJournal returns if folder is a world that was being downloaded and never saved,
+// worlds a running bedrocktool is still downloading, this one included, dont count
+func HasJournal(folder string) bool {
+	data, err := os.ReadFile(filepath.Join(folder, journalName))
+	if err != nil {
+		return false
+	}
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		// broken journals are left to Recover to report
+		return true
+	}
+	return j.PID == 0 || !utils.ProcessAlive(j.PID)
+}
+
+// Recover finishes a world that was left without saving by a crash,
+// it is moved to a new folder so the next download with the same name doesnt replace it
+func Recover(folder string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(folder, journalName))
+	if err != nil {
+		return "", err
+	}
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return "", fmt.Errorf("journal: %w", err)
+	}
+
+	recovered := folder + "-recovered-" + time.Now().Format("2006-01-02_15-04-05")
+	if err := os.Rename(folder, recovered); err != nil {
+		return "", err
+	}
+
+	// opening the db replays the leveldb log of everything that was flushed
+	provider, err := mcdb.Config{
+		Log: slog.Default(),
+		LDBOptions: &opt.Options{
+			Compression: opt.DefaultCompression,
+		},
+		Blocks: world.DefaultBlockRegistry,
+	}.Open(recovered)
+	if err != nil {
+		return "", err
+	}
+
+	s := provider.Settings()
+	s.Name = j.Name + " (recovered)"
+	s.Spawn = j.Spawn
+	s.Time = j.Time
+	ld := provider.LevelDat()
+	ld.CheatsEnabled = true
+	ld.RandomTickSpeed = 0
+	if j.VoidGen {
+		ld.FlatWorldLayers = voidFlatWorldLayers
+		ld.Generator = 2
+	}
+	provider.SaveSettings(s)
+	if err := provider.Close(); err != nil {
+		return "", err
+	}
+
+	os.Remove(filepath.Join(recovered, journalName))
+	return recovered, nil
+}
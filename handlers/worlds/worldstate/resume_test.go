@@ -0,0 +1,93 @@
+package worldstate
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/df-mc/dragonfly/server/world/mcdb"
+)
+
+func newResumedWorld(t *testing.T, policy MergePolicy) *World {
+	t.Helper()
+	w, err := New(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := mcdb.Config{
+		Log:    slog.Default(),
+		Blocks: world.DefaultBlockRegistry,
+	}.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { provider.Close() })
+	w.provider = provider
+	w.dimension = world.Overworld
+	w.resumed = true
+	w.mergePolicy = policy
+	return w
+}
+
+func TestParseMergePolicy(t *testing.T) {
+	if policy, err := ParseMergePolicy(""); err != nil || policy != MergeNewer {
+		t.Fatalf("expected newer by default, got %s %v", policy, err)
+	}
+	if _, err := ParseMergePolicy("older"); err == nil {
+		t.Fatal("unknown policy was accepted")
+	}
+}
+
+func TestMergeKeep(t *testing.T) {
+	w := newResumedWorld(t, MergeKeep)
+	saved, received := world.ChunkPos{0, 0}, world.ChunkPos{1, 0}
+	w.savedChunks[saved] = true
+	w.savedChunks[received] = false
+
+	if !w.keepSaved(saved) || w.keepSaved(received) {
+		t.Fatal("only the saved chunk should be kept")
+	}
+
+	ent := []chunk.Entity{{ID: 1, Data: map[string]any{"identifier": "minecraft:pig"}}}
+	w.storeEntities(map[world.ChunkPos][]chunk.Entity{saved: ent, received: ent})
+	if _, ok := w.flushedEntities[saved]; ok {
+		t.Fatal("entities of a kept chunk were overwritten")
+	}
+	if _, ok := w.flushedEntities[received]; !ok {
+		t.Fatal("entities of a new chunk werent stored")
+	}
+
+	// chunks stored this session dont clear the saved entities
+	w.StoredChunks[saved] = struct{}{}
+	chunkEntities := map[world.ChunkPos][]chunk.Entity{}
+	w.storeEntities(chunkEntities)
+	if _, ok := chunkEntities[saved]; ok {
+		t.Fatal("keep cleared the entities of a saved chunk")
+	}
+}
+
+func TestMergeNewer(t *testing.T) {
+	w := newResumedWorld(t, MergeNewer)
+	pos := world.ChunkPos{0, 0}
+	w.savedChunks[pos] = true
+	if w.keepSaved(pos) {
+		t.Fatal("newer never keeps saved chunks")
+	}
+
+	// a chunk received again without entities has its saved ones cleared
+	w.StoredChunks[pos] = struct{}{}
+	chunkEntities := map[world.ChunkPos][]chunk.Entity{}
+	w.storeEntities(chunkEntities)
+	if ents, ok := chunkEntities[pos]; !ok || ents != nil {
+		t.Fatal("entities of a chunk received again werent cleared")
+	}
+
+	// without resuming nothing is kept
+	w.resumed = false
+	w.mergePolicy = MergeKeep
+	if w.keepSaved(pos) {
+		t.Fatal("a new world has no saved chunks to keep")
+	}
+}
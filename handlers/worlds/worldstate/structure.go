@@ -0,0 +1,45 @@
+package worldstate
+
+import (
+	"math"
+
+	"github.com/bedrock-tool/bedrocktool/utils/schematic"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"golang.org/x/exp/maps"
+)
+
+// Structure cuts the blocks between a and b out of the chunks downloaded so far
+func (w *World) Structure(a, b cube.Pos, withEntities bool) (*schematic.Structure, error) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	w.applyBlockUpdates()
+
+	s, err := schematic.Extract(w.BlockRegistry, a, b, func(pos world.ChunkPos) (*chunk.Chunk, map[cube.Pos]map[string]any, error) {
+		ch, ok, err := w.loadChunkLocked(pos)
+		if !ok {
+			return nil, nil, err
+		}
+		return ch.Chunk, ch.BlockEntities, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if withEntities {
+		for _, ent := range w.memState.entities {
+			pos := cube.Pos{
+				int(math.Floor(float64(ent.Position.X()))),
+				int(math.Floor(float64(ent.Position.Y()))),
+				int(math.Floor(float64(ent.Position.Z()))),
+			}
+			if !s.Contains(pos) {
+				continue
+			}
+			links := maps.Keys(w.memState.entityLinks[ent.UniqueID])
+			s.AddEntity(ent.ToChunkEntity(links).Data)
+		}
+	}
+	return s, nil
+}
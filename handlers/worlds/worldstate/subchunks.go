@@ -0,0 +1,164 @@
+package worldstate
+
+import (
+	"cmp"
+	"maps"
+	"math/bits"
+	"slices"
+	"time"
+
+	"github.com/df-mc/dragonfly/server/world"
+)
+
+const (
+	// how often a missing sub chunk is requested again before giving up on it
+	maxSubChunkRetries = 3
+	// how long to wait for a response before requesting again
+	subChunkRetryDelay = 5 * time.Second
+)
+
+type dimensionChunk struct {
+	dimension int
+	pos       world.ChunkPos
+}
+
+// subChunkSet has a bit for each sub chunk of a column, by index from the bottom of the dimension
+type subChunkSet struct {
+	bits      [4]uint64
+	retries   int
+	requested time.Time
+}
+
+func (s *subChunkSet) set(i int, v bool) {
+	if i < 0 || i >= len(s.bits)*64 {
+		return
+	}
+	if v {
+		s.bits[i/64] |= 1 << (i % 64)
+	} else {
+		s.bits[i/64] &^= 1 << (i % 64)
+	}
+}
+
+func (s *subChunkSet) empty() bool {
+	return s.bits == [4]uint64{}
+}
+
+func (s *subChunkSet) indices() (out []int) {
+	for word, b := range s.bits {
+		for b != 0 {
+			i := bits.TrailingZeros64(b)
+			out = append(out, word*64+i)
+			b &^= 1 << i
+		}
+	}
+	return out
+}
+
+func (w *World) dimensionChunk(pos world.ChunkPos) dimensionChunk {
+	dimension, _ := world.DimensionID(w.dimension)
+	return dimensionChunk{dimension, pos}
+}
+
+// ExpectSubChunks marks the sub chunks at these y (in sub chunks) of a column as requested and missing until they arrive
+func (w *World) ExpectSubChunks(pos world.ChunkPos, subYs []int) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	if !w.inAreas(pos) {
+		return
+	}
+	set := &subChunkSet{requested: time.Now()}
+	for _, y := range subYs {
+		set.set(y-w.dimRange[0]>>4, true)
+	}
+	key := w.dimensionChunk(pos)
+	if set.empty() {
+		delete(w.missingSubChunks, key)
+		return
+	}
+	w.missingSubChunks[key] = set
+}
+
+// ReceivedSubChunk marks a sub chunk as received, returns if the column still misses any
+func (w *World) ReceivedSubChunk(pos world.ChunkPos, subY int) bool {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	key := w.dimensionChunk(pos)
+	set, ok := w.missingSubChunks[key]
+	if !ok {
+		return false
+	}
+	set.set(subY-w.dimRange[0]>>4, false)
+	if set.empty() {
+		delete(w.missingSubChunks, key)
+		return false
+	}
+	return true
+}
+
+// HasMissingSubChunks returns if sub chunks of the column in the current dimension were never received
+func (w *World) HasMissingSubChunks(pos world.ChunkPos) bool {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	_, ok := w.missingSubChunks[w.dimensionChunk(pos)]
+	return ok
+}
+
+// RetryMissingSubChunks returns up to limit columns within radius chunks of center with the sub chunk y that are still missing,
+// each column is only returned a few times
+func (w *World) RetryMissingSubChunks(center world.ChunkPos, radius int32, limit int) map[world.ChunkPos][]int {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	dimension, _ := world.DimensionID(w.dimension)
+	out := make(map[world.ChunkPos][]int)
+	for key, set := range w.missingSubChunks {
+		if len(out) >= limit {
+			break
+		}
+		if key.dimension != dimension || set.retries >= maxSubChunkRetries || time.Since(set.requested) < subChunkRetryDelay {
+			continue
+		}
+		dx, dz := int64(key.pos[0])-int64(center[0]), int64(key.pos[1])-int64(center[1])
+		if dx*dx+dz*dz > int64(radius)*int64(radius) {
+			continue
+		}
+		set.retries++
+		set.requested = time.Now()
+		var ys []int
+		for _, i := range set.indices() {
+			ys = append(ys, i+w.dimRange[0]>>4)
+		}
+		out[key.pos] = ys
+	}
+	return out
+}
+
+// PartialChunk is a chunk that some sub chunks were never received for
+type PartialChunk struct {
+	Dimension int            `json:"dimension"`
+	Pos       world.ChunkPos `json:"pos"`
+	// index of the missing sub chunks from the bottom of the dimension
+	Missing []int `json:"missing_sub_chunks"`
+}
+
+// PartialChunks returns the chunks in all dimensions that are missing sub chunks
+func (w *World) PartialChunks() []PartialChunk {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	keys := slices.SortedFunc(maps.Keys(w.missingSubChunks), func(a, b dimensionChunk) int {
+		return cmp.Or(
+			cmp.Compare(a.dimension, b.dimension),
+			cmp.Compare(a.pos[0], b.pos[0]),
+			cmp.Compare(a.pos[1], b.pos[1]),
+		)
+	})
+	out := make([]PartialChunk, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, PartialChunk{
+			Dimension: key.dimension,
+			Pos:       key.pos,
+			Missing:   w.missingSubChunks[key].indices(),
+		})
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package worldstate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/df-mc/dragonfly/server/world"
+)
+
+func TestRetryMissingSubChunks(t *testing.T) {
+	w, err := New(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	near := world.ChunkPos{3, -4}
+	// far enough away that the squared distance doesnt fit in an int32
+	far := world.ChunkPos{50000, 0}
+	for _, pos := range []world.ChunkPos{near, far, {-50000, 50000}} {
+		set := &subChunkSet{}
+		set.set(2, true)
+		w.missingSubChunks[dimensionChunk{0, pos}] = set
+	}
+
+	retry := w.RetryMissingSubChunks(world.ChunkPos{}, 5, 10)
+	if len(retry) != 1 || len(retry[near]) != 1 || retry[near][0] != 2 {
+		t.Fatalf("expected only %v to be retried, got %v", near, retry)
+	}
+
+	retry = w.RetryMissingSubChunks(far, 5, 10)
+	if len(retry) != 1 || retry[far] == nil {
+		t.Fatalf("expected only %v to be retried, got %v", far, retry)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"math"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -56,6 +57,7 @@ type World struct {
 	dimRange             cube.Range
 	dimensionDefinitions map[int]protocol.DimensionDefinition
 	StoredChunks         map[world.ChunkPos]struct{}
+	emptyChunks          map[world.ChunkPos]struct{} // received but not stored because they are only air
 
 	stateLock sync.Mutex
 	memState  *memoryState
@@ -69,11 +71,13 @@ type World struct {
 
 	players map[uuid.UUID]*player
 
-	VoidGen  bool
-	timeSync time.Time
-	time     int
-	Name     string
-	Folder   string
+	VoidGen bool
+	// how often chunks and entities are written to disk while downloading
+	FlushInterval time.Duration
+	timeSync      time.Time
+	time          int
+	Name          string
+	Folder        string
 
 	UseHashedRids    bool
 	blockUpdatesLock sync.Mutex
@@ -81,13 +85,56 @@ type World struct {
 	onChunkUpdate    func(pos world.ChunkPos, chunk *chunk.Chunk)
 	IgnoredChunks    map[world.ChunkPos]bool
 
+	// set when adding to a world saved by an earlier run
+	resumed     bool
+	mergePolicy MergePolicy
+	savedChunks map[world.ChunkPos]bool
+
+	// only chunks in these are kept, all if empty
+	areas []Area
+
+	// record every block update to the history file next to the world
+	RecordHistory bool
+	history       []BlockChange
+
+	// chunks stored in the dimensions that were left
+	otherDimensionChunks int
+	// sub chunks that were requested and not received yet
+	missingSubChunks map[dimensionChunk]*subChunkSet
+	started          time.Time
+
+	// chunks with entities written to the provider by a flush
+	flushedEntities map[world.ChunkPos]struct{}
+	playerPos       mgl32.Vec3
+
 	log *logrus.Entry
 }
 
+// MergePolicy decides what happens to chunks that are already in a resumed world
+type MergePolicy string
+
+const (
+	// chunks received now replace the saved ones
+	MergeNewer MergePolicy = "newer"
+	// saved chunks are never overwritten
+	MergeKeep MergePolicy = "keep"
+)
+
+func ParseMergePolicy(s string) (MergePolicy, error) {
+	switch policy := MergePolicy(s); policy {
+	case "":
+		return MergeNewer, nil
+	case MergeNewer, MergeKeep:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown merge policy %s, has to be %s or %s", s, MergeNewer, MergeKeep)
+}
+
 type blockUpdate struct {
 	rid   uint32
 	pos   protocol.BlockPos
 	layer uint8
+	time  time.Time
 }
 
 type Map struct {
@@ -113,12 +160,17 @@ func New(ctx context.Context, dimensionDefinitions map[int]protocol.DimensionDef
 		cancelCtx: cancel,
 
 		StoredChunks:         make(map[world.ChunkPos]struct{}),
+		emptyChunks:          make(map[world.ChunkPos]struct{}),
 		dimensionDefinitions: dimensionDefinitions,
 		memState:             newWorldState(),
 		players:              make(map[uuid.UUID]*player),
 		blockUpdates:         make(map[world.ChunkPos][]blockUpdate),
 		onChunkUpdate:        onChunkUpdate,
 		IgnoredChunks:        make(map[world.ChunkPos]bool),
+		savedChunks:          make(map[world.ChunkPos]bool),
+		flushedEntities:      make(map[world.ChunkPos]struct{}),
+		missingSubChunks:     make(map[dimensionChunk]*subChunkSet),
+		started:              time.Now(),
 		log:                  logrus.WithFields(logrus.Fields{"part": "world"}),
 	}
 
@@ -131,7 +183,10 @@ func (w *World) storeMemToProvider() error {
 	}
 	if w.provider == nil {
 		w.log.Debugf("Opening provider in %s", w.Folder)
-		utils.RemoveTree(w.Folder)
+		if !w.resumed {
+			utils.RemoveTree(w.Folder)
+			os.Remove(w.Folder + historyExt)
+		}
 		os.MkdirAll(w.Folder, 0o777)
 		provider, err := mcdb.Config{
 			Log: slog.Default(),
@@ -167,6 +222,10 @@ func (w *World) storeMemToProvider() error {
 		if empty {
 			continue
 		}
+		if w.keepSaved(pos) {
+			delete(w.memState.chunks, pos)
+			continue
+		}
 
 		var blockEntities []chunk.BlockEntity
 		for pos, ent := range ch.BlockEntities {
@@ -188,6 +247,40 @@ func (w *World) storeMemToProvider() error {
 	return nil
 }
 
+// keepSaved returns if a chunk is in the resumed world already and the policy says to leave it
+func (w *World) keepSaved(pos world.ChunkPos) bool {
+	if !w.resumed || w.mergePolicy != MergeKeep {
+		return false
+	}
+	saved, ok := w.savedChunks[pos]
+	if !ok {
+		_, err := w.provider.LoadColumn(pos, w.dimension)
+		saved = err == nil
+		w.savedChunks[pos] = saved
+	}
+	return saved
+}
+
+// SetAreas limits the chunks that are kept to the ones in areas
+func (w *World) SetAreas(areas []Area) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	w.areas = areas
+}
+
+func (w *World) inAreas(pos world.ChunkPos) bool {
+	if len(w.areas) == 0 {
+		return true
+	}
+	dimension, _ := world.DimensionID(w.dimension)
+	for _, area := range w.areas {
+		if area.Contains(dimension, pos) {
+			return true
+		}
+	}
+	return false
+}
+
 func (w *World) Dimension() world.Dimension {
 	return w.dimension
 }
@@ -220,6 +313,9 @@ func (w *World) StoreChunk(pos world.ChunkPos, ch *Chunk) (err error) {
 }
 
 func (w *World) storeChunkLocked(pos world.ChunkPos, ch *Chunk) (err error) {
+	if !w.inAreas(pos) {
+		return nil
+	}
 	var empty = true
 	for _, sub := range ch.Sub() {
 		if !sub.Empty() {
@@ -227,21 +323,28 @@ func (w *World) storeChunkLocked(pos world.ChunkPos, ch *Chunk) (err error) {
 			break
 		}
 	}
-	if !empty {
+	if empty {
+		w.emptyChunks[pos] = struct{}{}
+	} else {
+		delete(w.emptyChunks, pos)
 		w.StoredChunks[pos] = struct{}{}
 		w.onChunkUpdate(pos, ch.Chunk)
 		// only start saving once a non empty chunk is received
 		w.onceOpen.Do(func() {
 			go func() {
-				t := time.NewTicker(10 * time.Second)
+				interval := w.FlushInterval
+				if interval <= 0 {
+					interval = DefaultFlushInterval
+				}
+				t := time.NewTicker(interval)
+				defer t.Stop()
 				for {
 					select {
 					case <-w.ctx.Done():
 						return
 					case <-t.C:
 						w.stateLock.Lock()
-						w.applyBlockUpdates()
-						w.storeMemToProvider()
+						w.flushLocked()
 						w.stateLock.Unlock()
 					}
 				}
@@ -287,11 +390,11 @@ func (w *World) loadChunkLocked(pos world.ChunkPos) (*Chunk, bool, error) {
 	return nil, false, nil
 }
 
-func (w *World) QueueBlockUpdate(pos protocol.BlockPos, ridTo uint32, layer uint8) {
+func (w *World) QueueBlockUpdate(pos protocol.BlockPos, ridTo uint32, layer uint8, t time.Time) {
 	cp := world.ChunkPos{pos.X() >> 4, pos.Z() >> 4}
 	w.blockUpdatesLock.Lock()
 	defer w.blockUpdatesLock.Unlock()
-	w.blockUpdates[cp] = append(w.blockUpdates[cp], blockUpdate{rid: ridTo, pos: pos, layer: layer})
+	w.blockUpdates[cp] = append(w.blockUpdates[cp], blockUpdate{rid: ridTo, pos: pos, layer: layer, time: t})
 }
 
 func (w *World) SetBlockNBT(pos cube.Pos, nbt map[string]any, merge bool) error {
@@ -307,8 +410,8 @@ func (w *World) SetBlockNBT(pos cube.Pos, nbt map[string]any, merge bool) error
 		return nil
 	}
 
-	if merge {
-		maps.Copy(ch.BlockEntities[pos], nbt)
+	if existing, ok := ch.BlockEntities[pos]; merge && ok {
+		maps.Copy(existing, nbt)
 	} else {
 		ch.BlockEntities[pos] = nbt
 	}
@@ -337,6 +440,45 @@ var ErrIgnoreEntity = errors.New("ignore entity")
 func (w *World) ActEntity(id entity.RuntimeID, create bool, fn func(ent *entity.Entity) error) error {
 	w.stateLock.Lock()
 	defer w.stateLock.Unlock()
+	return w.actEntityLocked(id, create, fn)
+}
+
+// ActAddedEntity is ActEntity for an entity the server adds,
+// one that was detached by a reconnect is taken over by its unique id
+func (w *World) ActAddedEntity(id entity.RuntimeID, uniqueID entity.UniqueID, fn func(ent *entity.Entity) error) error {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	if w.memState.GetEntity(id) == nil {
+		if oldID, ok := w.memState.uniqueIDsToRuntimeIDs[uniqueID]; ok && oldID >= detachedRuntimeIDs {
+			ent := w.memState.entities[oldID]
+			delete(w.memState.entities, oldID)
+			ent.RuntimeID = id
+			w.memState.StoreEntity(id, ent)
+		}
+	}
+	return w.actEntityLocked(id, true, fn)
+}
+
+// detachedRuntimeIDs is where the ids of entities from a previous connection start, servers dont use them
+const detachedRuntimeIDs entity.RuntimeID = 1 << 63
+
+// DetachEntities moves the entities to ids no server uses when the connection changes,
+// they are still saved and get their new id when the server adds them again
+func (w *World) DetachEntities() {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	entities := w.memState.entities
+	w.memState.entities = make(map[entity.RuntimeID]*entity.Entity, len(entities))
+	clear(w.memState.uniqueIDsToRuntimeIDs)
+	next := detachedRuntimeIDs
+	for _, ent := range entities {
+		ent.RuntimeID = next
+		w.memState.StoreEntity(next, ent)
+		next++
+	}
+}
+
+func (w *World) actEntityLocked(id entity.RuntimeID, create bool, fn func(ent *entity.Entity) error) error {
 	var new bool
 	ent := w.memState.GetEntity(id)
 	if ent == nil {
@@ -367,6 +509,9 @@ func (w *World) ActEntity(id entity.RuntimeID, create bool, fn func(ent *entity.
 }
 
 func (w *World) PlayerMove(playerPos mgl32.Vec3, entityRenderDistance float32, teleport int) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+	w.playerPos = playerPos
 	// all entities that are deleted and in the entity render range:
 	// update distance they were *not* seen at to the current distance
 	for _, ent := range w.memState.entities {
@@ -429,6 +574,9 @@ func (w *World) applyBlockUpdates() {
 	defer w.blockUpdatesLock.Unlock()
 	for pos, updates := range w.blockUpdates {
 		delete(w.blockUpdates, pos)
+		if !w.inAreas(pos) {
+			continue
+		}
 		ch, ok, err := w.loadChunkLocked(world.ChunkPos(pos))
 		if !ok {
 			w.log.Warnf("Chunk updates for a chunk we dont have pos = %v", pos)
@@ -441,6 +589,9 @@ func (w *World) applyBlockUpdates() {
 
 		for _, update := range updates {
 			x, y, z := blockPosInChunk(update.pos)
+			if w.RecordHistory {
+				w.recordChange(update, ch.Block(x, y, z, update.layer))
+			}
 			ch.SetBlock(x, y, z, update.layer, update.rid)
 		}
 		err = w.storeChunkLocked(pos, ch)
@@ -463,6 +614,19 @@ func (w *World) Open(name string, folder string) {
 	w.opened = true
 }
 
+// OpenExisting adds to a world saved before instead of starting a new one
+func (w *World) OpenExisting(folder string, policy MergePolicy) error {
+	if _, err := os.Stat(filepath.Join(folder, "level.dat")); err != nil {
+		return err
+	}
+	w.Open(filepath.Base(folder), folder)
+	w.stateLock.Lock()
+	w.resumed = true
+	w.mergePolicy = policy
+	w.stateLock.Unlock()
+	return nil
+}
+
 // Rename moves the folder and reopens it
 func (w *World) Rename(name, folder string) error {
 	w.stateLock.Lock()
@@ -490,6 +654,9 @@ func (w *World) Rename(name, folder string) error {
 		}
 		w.provider = provider
 	}
+	if err := os.Rename(w.Folder+historyExt, folder+historyExt); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
 	w.Folder = folder
 	w.Name = name
 	return nil
@@ -497,6 +664,8 @@ func (w *World) Rename(name, folder string) error {
 
 var errFinished = errors.New("finished")
 
+const voidFlatWorldLayers = `{"biome_id":1,"block_layers":[{"block_data":0,"block_id":0,"count":1},{"block_data":0,"block_id":0,"count":2},{"block_data":0,"block_id":0,"count":1}],"encoding_version":3,"structure_options":null}`
+
 func (w *World) Save(
 	player proxy.Player, playerData map[string]any,
 	behaviorPack *behaviourpack.Pack,
@@ -598,6 +767,9 @@ func (w *World) finalizeProvider(
 	if err != nil {
 		return err
 	}
+	if err := w.writeHistory(); err != nil {
+		w.log.WithError(err).Error("Failed to write block history")
+	}
 
 	messages.SendEvent(&messages.EventProcessingWorldUpdate{
 		WorldName: w.Name,
@@ -606,44 +778,7 @@ func (w *World) finalizeProvider(
 
 	logrus.Tracef("entityRenderDistance: %.5f", entityRenderDistance)
 
-	chunkEntities := make(map[world.ChunkPos][]chunk.Entity)
-	for _, ent := range w.memState.entities {
-		var ignore bool
-		for _, ex := range excludedMobs {
-			if ok, err := path.Match(ex, ent.EntityType); ok {
-				w.log.Debugf("Excluding: %s %v", ent.EntityType, ent.Position)
-				ignore = true
-				break
-			} else if err != nil {
-				w.log.Warn(err)
-			}
-		}
-		if ignore {
-			continue
-		}
-
-		var diff float32
-		shouldCull := entityCull(ent, entityRenderDistance, &diff)
-
-		if shouldCull {
-			if entityCulling {
-				logrus.Tracef("dropping entity %s, dist: %.5f diff: %.5f", ent.EntityType, ent.DeletedDistance, diff)
-				continue
-			} else {
-				ent.Tags = append(ent.Tags, "removed")
-			}
-		}
-		cp := world.ChunkPos{int32(ent.Position.X()) >> 4, int32(ent.Position.Z()) >> 4}
-		links := maps.Keys(w.memState.entityLinks[ent.UniqueID])
-		chunkEntities[cp] = append(chunkEntities[cp], ent.ToChunkEntity(links))
-	}
-
-	for cp, v := range chunkEntities {
-		err := w.provider.StoreEntities(cp, w.dimension, v)
-		if err != nil {
-			w.log.Error(err)
-		}
-	}
+	w.storeEntities(w.groupEntities(excludedMobs, entityCulling, entityRenderDistance))
 
 	err = w.provider.SaveLocalPlayerData(playerData)
 	if err != nil {
@@ -754,7 +889,7 @@ func (w *World) finalizeProvider(
 
 	// void world
 	if w.VoidGen {
-		ld.FlatWorldLayers = `{"biome_id":1,"block_layers":[{"block_data":0,"block_id":0,"count":1},{"block_data":0,"block_id":0,"count":2},{"block_data":0,"block_id":0,"count":1}],"encoding_version":3,"structure_options":null}`
+		ld.FlatWorldLayers = voidFlatWorldLayers
 		ld.Generator = 2
 	}
 
@@ -779,5 +914,80 @@ func (w *World) finalizeProvider(
 	}
 
 	w.provider.SaveSettings(s)
-	return w.provider.Close()
+	if err := w.provider.Close(); err != nil {
+		return err
+	}
+	w.removeJournal()
+	return nil
+}
+
+// groupEntities makes the saved form of the entities by chunk, culled ones are dropped or tagged
+func (w *World) groupEntities(excludedMobs []string, entityCulling bool, entityRenderDistance float32) map[world.ChunkPos][]chunk.Entity {
+	chunkEntities := make(map[world.ChunkPos][]chunk.Entity)
+	for _, ent := range w.memState.entities {
+		var ignore bool
+		for _, ex := range excludedMobs {
+			if ok, err := path.Match(ex, ent.EntityType); ok {
+				w.log.Debugf("Excluding: %s %v", ent.EntityType, ent.Position)
+				ignore = true
+				break
+			} else if err != nil {
+				w.log.Warn(err)
+			}
+		}
+		if ignore {
+			continue
+		}
+		if !w.inAreas(world.ChunkPos{int32(ent.Position.X()) >> 4, int32(ent.Position.Z()) >> 4}) {
+			continue
+		}
+
+		var diff float32
+		shouldCull := entityCull(ent, entityRenderDistance, &diff)
+
+		if shouldCull {
+			if entityCulling {
+				logrus.Tracef("dropping entity %s, dist: %.5f diff: %.5f", ent.EntityType, ent.DeletedDistance, diff)
+				continue
+			} else {
+				ent.Tags = append(ent.Tags, "removed")
+			}
+		}
+		cp := world.ChunkPos{int32(ent.Position.X()) >> 4, int32(ent.Position.Z()) >> 4}
+		links := maps.Keys(w.memState.entityLinks[ent.UniqueID])
+		chunkEntities[cp] = append(chunkEntities[cp], ent.ToChunkEntity(links))
+	}
+	return chunkEntities
+}
+
+// storeEntities writes the entities of each chunk, chunks that had entities flushed before and have none now are cleared
+func (w *World) storeEntities(chunkEntities map[world.ChunkPos][]chunk.Entity) {
+	if w.resumed && w.mergePolicy == MergeNewer {
+		// entities saved before in chunks that were received again are replaced
+		for pos := range w.StoredChunks {
+			if _, ok := chunkEntities[pos]; !ok {
+				chunkEntities[pos] = nil
+			}
+		}
+	}
+	for cp := range w.flushedEntities {
+		if _, ok := chunkEntities[cp]; !ok {
+			chunkEntities[cp] = nil
+		}
+	}
+	for cp, v := range chunkEntities {
+		if w.keepSaved(cp) {
+			continue
+		}
+		err := w.provider.StoreEntities(cp, w.dimension, v)
+		if err != nil {
+			w.log.Error(err)
+			continue
+		}
+		if len(v) > 0 {
+			w.flushedEntities[cp] = struct{}{}
+		} else {
+			delete(w.flushedEntities, cp)
+		}
+	}
 }
@@ -3,13 +3,56 @@ declare const console: {
 };
 
 function displayChatMessage(msg: string);
+/**
+ * Only works in the worlds handler.
+ */
 function setIngameMap(enabled: boolean);
 
+/**
+ * Runs the callback once after delay milliseconds of session time, which is the time of the received packets.
+ */
+function setTimeout(callback: (...args: any[]) => void, delay?: number, ...args: any[]): number;
+/**
+ * Runs the callback every delay milliseconds of session time.
+ */
+function setInterval(callback: (...args: any[]) => void, delay?: number, ...args: any[]): number;
+function clearTimeout(id: number): void;
+function clearInterval(id: number): void;
+
+declare const session: {
+	/**
+	 * Sends a packet to the server as if the client sent it.
+	 *
+	 * The packet has to be in `sendPackets` of the permissions.
+	 *
+	 * @param name - The name of the packet, the same as in the Packet event.
+	 * @param fields - The fields of the packet to set, the rest are zero.
+	 *
+	 * @example
+	 * session.sendToServer('ContainerClose', { WindowID: 1, ServerSide: false });
+	 */
+	sendToServer(name: string, fields?: {[k: string]: any}): void;
+	/**
+	 * Sends a packet to the client as if the server sent it.
+	 * The packet has to be in `sendPackets` of the permissions.
+	 */
+	sendToClient(name: string, fields?: {[k: string]: any}): void;
+	/**
+	 * Adds an in-game command, returning false from the callback means it failed.
+	 *
+	 * @example
+	 * session.addCommand('hello', 'says hello', (args) => {
+	 *     displayChatMessage(`hello ${args.join(' ')}`);
+	 * });
+	 */
+	addCommand(name: string, description: string, callback: (args: string[]) => boolean | void): void;
+};
+
 
 /**
  * Names of events that can be registered.
  */
-declare type EventNames = 'EntityAdd' | 'EntityUpdate' | 'EntityPropertyChange' | 'ChunkAdd' | 'BlockUpdate' | 'SpawnParticle' | 'Packet';
+declare type EventNames = 'EntityAdd' | 'EntityUpdate' | 'EntityPropertyChange' | 'ChunkAdd' | 'BlockUpdate' | 'SpawnParticle' | 'Packet' | 'BeforeSave';
 
 declare type EntityUpdate = {
 	Entity: Entity;
@@ -83,6 +126,14 @@ declare type PacketCallback = (name: string, packet: any, toServer: boolean, tim
 
 declare type ChunkDataCallback = (pos: [number, number]) => void;
 
+/**
+ * Callback for the `BeforeSave` event, runs before a world is written to disk.
+ * 
+ * @param world - The world that is being saved, edits to it are saved.
+ * @param name - The name of the world.
+ */
+declare type BeforeSaveCallback = (world: World, name: string) => void;
+
 declare const events: {
     /**
      * Registers a callback function to be executed when a specified event occurs.
@@ -239,6 +290,8 @@ declare const events: {
     register(name: 'Packet', callback: PacketCallback): void;
 
 	register(name: "ChunkData", callback: ChunkDataCallback): void;
+
+	register(name: "BeforeSave", callback: BeforeSaveCallback): void;
 };
 
 interface FileWriter {
@@ -252,9 +305,29 @@ interface FileReader {
 }
 
 interface FileSystem {
+	/**
+	 * Creates a file relative to the data folder, in one of the folders in `fileSystem` of the permissions.
+	 */
 	create(name: string): FileWriter;
 }
 
+/**
+ * The permissions of a script, read from `name.permissions.json` next to `name.js` or `name.ts`,
+ * or `permissions.json` in a script folder. Without it a script can't write files or send packets.
+ * Modules can only be imported from the folder of the script.
+ * A callback that runs longer than its time budget stops the script until it changes.
+ */
+declare type Permissions = {
+	/** folders in the data folder files can be created in, "." for all of it */
+	fileSystem?: string[];
+	/** names of the packets that can be sent, "*" for all */
+	sendPackets?: string[];
+	/** milliseconds one callback may run, 100 by default */
+	timeBudget?: number;
+	/** hosts that can be connected to, reserved, scripts can't open connections yet */
+	network?: string[];
+};
+
 interface BlockState {
 	name: string;
 	state: {[k: string]: any};
@@ -278,8 +351,43 @@ interface Chunks {
 	get(x: number, z: number): Chunk|null;
 }
 
+interface WorldEntity {
+	Entity: Entity;
+	Metadata: EntityMetadata;
+}
+
+/**
+ * Queries and edits of the downloaded world, chunks that were not downloaded are skipped.
+ */
+interface World {
+	/** null if the chunk was not downloaded, layer is 0 or 1 */
+	getBlock(x: number, y: number, z: number, layer?: number): BlockState|null;
+	/** returns false if the chunk was not downloaded, the block entity is removed if the block changes */
+	setBlock(x: number, y: number, z: number, name: string, state?: {[k: string]: any}, layer?: number): boolean;
+	/** sets all blocks between from and to, only blocks named replace if it is given, returns how many changed */
+	fill(from: [number, number, number], to: [number, number, number], name: string, state?: {[k: string]: any}, replace?: string): number;
+	getBlockEntity(x: number, y: number, z: number): {[k: string]: any}|null;
+	/** merge keeps the existing keys that are not in data */
+	setBlockEntity(x: number, y: number, z: number, data: {[k: string]: any}, merge?: boolean): boolean;
+	removeBlockEntity(x: number, y: number, z: number): boolean;
+	/** copies of the entities of the current dimension, changes are saved with setEntity */
+	getEntities(): WorldEntity[];
+	/** stores a changed entity from getEntities, returns false if it is gone */
+	setEntity(entity: Entity): boolean;
+	/** returns false if there was no such entity */
+	removeEntity(runtimeID: number): boolean;
+}
+
 declare const fs: FileSystem;
 
+/**
+ * Only works in the worlds handler.
+ */
+declare const world: World;
+
+/**
+ * Only works in the worlds handler.
+ */
 declare const chunks: Chunks;
 
 /**
@@ -0,0 +1,36 @@
+package subcommands
+
+import (
+	"context"
+
+	"github.com/bedrock-tool/bedrocktool/ui/api"
+	"github.com/bedrock-tool/bedrocktool/utils/commands"
+)
+
+type APISettings struct {
+	Listen string `opt:"Listen Address" flag:"listen" default:"127.0.0.1:8089" desc:"address the api listens on"`
+	Token  string `opt:"Token" flag:"token" desc:"require this token in an Authorization: Bearer header or the token query parameter, a random one is logged when not set"`
+}
+
+type APICMD struct{}
+
+func (APICMD) Name() string {
+	return "api"
+}
+
+func (APICMD) Description() string {
+	return "run a local http api that starts commands and streams their events"
+}
+
+func (APICMD) Settings() any {
+	return new(APISettings)
+}
+
+func (APICMD) Run(ctx context.Context, settings any) error {
+	apiSettings := settings.(*APISettings)
+	return api.New(ctx, apiSettings.Token).ListenAndServe(apiSettings.Listen)
+}
+
+func init() {
+	commands.RegisterCommand(&APICMD{})
+}
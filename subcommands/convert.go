@@ -0,0 +1,49 @@
+package subcommands
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bedrock-tool/bedrocktool/utils"
+	"github.com/bedrock-tool/bedrocktool/utils/anvil"
+	"github.com/bedrock-tool/bedrocktool/utils/commands"
+)
+
+type ConvertSettings struct {
+	WorldPath string `opt:"World Path" flag:"world" desc:"world folder to convert"`
+	Out       string `opt:"Out Path" flag:"out" desc:"java world folder to write, defaults to the world path with -java"`
+}
+
+type ConvertCMD struct{}
+
+func (ConvertCMD) Name() string {
+	return "convert"
+}
+
+func (ConvertCMD) Description() string {
+	return "convert a downloaded world to java edition"
+}
+
+func (ConvertCMD) Settings() any {
+	return new(ConvertSettings)
+}
+
+func (ConvertCMD) Run(ctx context.Context, settings any) error {
+	convertSettings := settings.(*ConvertSettings)
+	if convertSettings.WorldPath == "" {
+		return fmt.Errorf("missing -world")
+	}
+	worldPath := path.Clean(strings.ReplaceAll(convertSettings.WorldPath, "\\", "/"))
+	out := convertSettings.Out
+	if out == "" {
+		out = worldPath + "-java"
+	}
+	out = path.Clean(strings.ReplaceAll(out, "\\", "/"))
+	return anvil.ConvertWorld(utils.PathData(worldPath), utils.PathData(out))
+}
+
+func init() {
+	commands.RegisterCommand(&ConvertCMD{})
+}
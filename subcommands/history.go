@@ -0,0 +1,135 @@
+package subcommands
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
+	"github.com/bedrock-tool/bedrocktool/utils"
+	"github.com/bedrock-tool/bedrocktool/utils/commands"
+	"github.com/sirupsen/logrus"
+)
+
+type HistorySettings struct {
+	WorldPath string `opt:"World Path" flag:"world" desc:"world folder that was downloaded with -history"`
+	List      bool   `opt:"List" flag:"list" desc:"print the recorded block changes"`
+	Areas     string `opt:"Areas" flag:"areas" desc:"only list changes in these areas, same format as the worlds -areas"`
+	At        string `opt:"At" flag:"at" desc:"write the world as it was at this time, like 2006-01-02 15:04:05"`
+	Every     string `opt:"Every" flag:"every" desc:"write a snapshot of the world every interval like 10m over the recorded time"`
+	Out       string `opt:"Out Path" flag:"out" desc:"folder to write the worlds to, defaults to the world folder"`
+}
+
+type HistoryCMD struct{}
+
+func (HistoryCMD) Name() string {
+	return "history"
+}
+
+func (HistoryCMD) Description() string {
+	return "list recorded block changes or rebuild a world as it was at a time"
+}
+
+func (HistoryCMD) Settings() any {
+	return new(HistorySettings)
+}
+
+var historyTimeLayouts = []string{time.DateTime, "2006-01-02 15:04", time.RFC3339}
+
+func parseHistoryTime(s string) (time.Time, error) {
+	for _, layout := range historyTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("time %q has to look like %s", s, time.DateTime)
+}
+
+func (HistoryCMD) Run(ctx context.Context, settings any) error {
+	historySettings := settings.(*HistorySettings)
+	if historySettings.WorldPath == "" {
+		return fmt.Errorf("missing -world")
+	}
+	folder := filepath.Clean(utils.PathData(historySettings.WorldPath))
+	changes, err := worldstate.ReadHistory(folder)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("no block changes recorded for %s", folder)
+	}
+	first := time.UnixMilli(changes[0].Time)
+	last := time.UnixMilli(changes[len(changes)-1].Time)
+	logrus.Infof("%d block changes from %s to %s", len(changes), first.Format(time.DateTime), last.Format(time.DateTime))
+
+	if historySettings.List {
+		areas, err := worldstate.ParseAreas(historySettings.Areas)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if len(areas) > 0 && !inAnyArea(areas, change) {
+				continue
+			}
+			fmt.Println(change)
+		}
+	}
+
+	outDir := filepath.Dir(folder)
+	if historySettings.Out != "" {
+		outDir = utils.PathData(historySettings.Out)
+	}
+	rewind := func(at time.Time) error {
+		outFolder := filepath.Join(outDir, filepath.Base(folder)+"-"+strings.ReplaceAll(at.Format(time.DateTime), ":", "-"))
+		undone, err := worldstate.Rewind(folder, outFolder, changes, at)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Wrote %s, undid %d changes", outFolder, undone)
+		return nil
+	}
+
+	if historySettings.At != "" {
+		at, err := parseHistoryTime(historySettings.At)
+		if err != nil {
+			return err
+		}
+		if err := rewind(at); err != nil {
+			return err
+		}
+	}
+
+	if historySettings.Every != "" {
+		every, err := time.ParseDuration(historySettings.Every)
+		if err != nil {
+			return err
+		}
+		if every <= 0 {
+			return fmt.Errorf("-every has to be more than 0")
+		}
+		for at := first.Truncate(every); at.Before(last); at = at.Add(every) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := rewind(at); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func inAnyArea(areas []worldstate.Area, change worldstate.BlockChange) bool {
+	for _, area := range areas {
+		if area.Contains(int(change.Dimension), change.ChunkPos()) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	commands.RegisterCommand(&HistoryCMD{})
+}
@@ -0,0 +1,89 @@
+package subcommands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bedrock-tool/bedrocktool/utils/commands"
+	"github.com/bedrock-tool/bedrocktool/utils/proxy/pcap2"
+	"github.com/sirupsen/logrus"
+)
+
+type PcapConvertSettings struct {
+	File string `opt:"File" flag:"file" type:"file" desc:"capture to convert, .pcap2 is converted to .pcapng and the other way around"`
+	Out  string `opt:"Out Path" flag:"out" desc:"file to write to, next to the input if empty"`
+}
+
+type PcapConvertCMD struct{}
+
+func (PcapConvertCMD) Name() string {
+	return "pcap-convert"
+}
+
+func (PcapConvertCMD) Description() string {
+	return "convert captures between pcap2 and pcapng for wireshark"
+}
+
+func (PcapConvertCMD) Settings() any {
+	return new(PcapConvertSettings)
+}
+
+func (PcapConvertCMD) Run(ctx context.Context, settings any) error {
+	convertSettings := settings.(*PcapConvertSettings)
+	if convertSettings.File == "" {
+		return fmt.Errorf("-file must be specified")
+	}
+
+	ext := strings.ToLower(filepath.Ext(convertSettings.File))
+	base := strings.TrimSuffix(convertSettings.File, filepath.Ext(convertSettings.File))
+	out := convertSettings.Out
+
+	in, err := os.Open(convertSettings.File)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	switch ext {
+	case ".pcap2":
+		if out == "" {
+			out = base + ".pcapng"
+		}
+		reader, err := pcap2.NewPcap2Reader(in)
+		if err != nil {
+			return err
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err = pcap2.ExportPcapng(reader, f); err != nil {
+			return err
+		}
+	case ".pcapng":
+		if out == "" {
+			out = base + ".pcap2"
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err = pcap2.ImportPcapng(in, f); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("dont know how to convert %s files", ext)
+	}
+
+	logrus.Infof("Wrote %s", out)
+	return nil
+}
+
+func init() {
+	commands.RegisterCommand(&PcapConvertCMD{})
+}
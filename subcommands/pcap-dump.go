@@ -0,0 +1,235 @@
+package subcommands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/commands"
+	"github.com/bedrock-tool/bedrocktool/utils/proxy/pcap2"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sirupsen/logrus"
+)
+
+type PcapDumpSettings struct {
+	File      string   `opt:"Capture File" flag:"file" type:"file,pcap2"`
+	Out       string   `opt:"Out Path" flag:"out" desc:"file to write to, stdout if empty"`
+	Packets   []string `opt:"Packets" flag:"packets" desc:"only dump these packets (names or ids) seperated by comma"`
+	Exclude   []string `opt:"Exclude Packets" flag:"exclude" desc:"packets (names or ids) to leave out seperated by comma"`
+	Direction string   `opt:"Direction" flag:"direction" desc:"only dump packets going to the 'server' or 'client'"`
+	From      string   `opt:"From" flag:"from" desc:"offset into the capture to start at (e.g. 10m)"`
+	To        string   `opt:"To" flag:"to" desc:"offset into the capture to stop at (e.g. 1h30m)"`
+}
+
+type PcapDumpCMD struct{}
+
+func (PcapDumpCMD) Name() string {
+	return "pcap-dump"
+}
+
+func (PcapDumpCMD) Description() string {
+	return "dump packets from a capture as json lines"
+}
+
+func (PcapDumpCMD) Settings() any {
+	return new(PcapDumpSettings)
+}
+
+type dumpedPacket struct {
+	Number    int             `json:"number"`
+	Time      time.Time       `json:"time"`
+	Offset    int64           `json:"offset_ms"`
+	Direction string          `json:"direction"`
+	ID        uint32          `json:"id"`
+	Name      string          `json:"name"`
+	Fields    json.RawMessage `json:"fields"`
+}
+
+// packetFilter selects packets from a capture
+type packetFilter struct {
+	include   map[uint32]bool
+	exclude   map[uint32]bool
+	direction string
+	from, to  time.Duration
+}
+
+func parsePacketIDs(names []string) (map[uint32]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	ids := make(map[uint32]bool, len(names))
+	for _, name := range names {
+		id, ok := pcap2.PacketIDByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown packet %s", name)
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// parseOffset parses a duration flag, empty means def
+func parseOffset(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func newPacketFilter(packets, exclude []string, direction, from, to string) (*packetFilter, error) {
+	var f packetFilter
+	var err error
+	if f.include, err = parsePacketIDs(packets); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = parsePacketIDs(exclude); err != nil {
+		return nil, err
+	}
+	switch direction {
+	case "", "server", "client":
+		f.direction = direction
+	default:
+		return nil, fmt.Errorf("invalid direction %s, has to be 'server' or 'client'", direction)
+	}
+	if f.from, err = parseOffset(from, 0); err != nil {
+		return nil, err
+	}
+	if f.to, err = parseOffset(to, time.Duration(1<<63-1)); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (f *packetFilter) match(entry pcap2.IndexEntry, offset time.Duration) bool {
+	if offset < f.from || offset > f.to {
+		return false
+	}
+	if f.direction == "server" && !entry.ToServer || f.direction == "client" && entry.ToServer {
+		return false
+	}
+	if f.include != nil && !f.include[entry.PacketID] {
+		return false
+	}
+	return !f.exclude[entry.PacketID]
+}
+
+func packetDirection(toServer bool) string {
+	if toServer {
+		return "serverbound"
+	}
+	return "clientbound"
+}
+
+func (PcapDumpCMD) Run(ctx context.Context, settings any) error {
+	dumpSettings := settings.(*PcapDumpSettings)
+	if dumpSettings.File == "" {
+		return fmt.Errorf("-file must be specified")
+	}
+
+	filter, err := newPacketFilter(
+		dumpSettings.Packets, dumpSettings.Exclude,
+		dumpSettings.Direction,
+		dumpSettings.From, dumpSettings.To,
+	)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(dumpSettings.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader, err := pcap2.NewPcap2Reader(f)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if dumpSettings.Out != "" {
+		outFile, err := os.Create(dumpSettings.Out)
+		if err != nil {
+			return err
+		}
+		defer outFile.Close()
+		out = outFile
+	}
+	bw := bufio.NewWriter(out)
+	defer bw.Flush()
+	return dumpPackets(ctx, reader, filter, bw)
+}
+
+// dumpPackets reads the capture from the start and writes the packets that match as json lines,
+// only those and the item registry are decoded
+func dumpPackets(ctx context.Context, reader *pcap2.Pcap2Reader, filter *packetFilter, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	var start time.Time
+	for i := 0; ; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		payload, toServer, receivedTime, err := reader.ReadRaw()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if i == 0 {
+			start = receivedTime
+		}
+
+		var header packet.Header
+		if err := header.Read(bytes.NewBuffer(payload)); err != nil {
+			logrus.Warnf("packet %d: %s", i, err)
+			continue
+		}
+		offset := receivedTime.Sub(start)
+		match := filter.match(pcap2.IndexEntry{
+			Time:     receivedTime,
+			PacketID: header.PacketID,
+			ToServer: toServer,
+		}, offset)
+		// the item registry is needed to decode items correctly
+		if !match && header.PacketID != packet.IDItemRegistry {
+			continue
+		}
+
+		pk, err := reader.DecodePacket(payload, toServer, receivedTime)
+		if err != nil {
+			logrus.Warnf("packet %d: %s", i, err)
+			continue
+		}
+		if !match {
+			continue
+		}
+
+		fields, err := json.Marshal(pk)
+		if err != nil {
+			fields, _ = json.Marshal(map[string]string{"error": err.Error()})
+		}
+		err = enc.Encode(dumpedPacket{
+			Number:    i,
+			Time:      receivedTime,
+			Offset:    offset.Milliseconds(),
+			Direction: packetDirection(toServer),
+			ID:        pk.ID(),
+			Name:      pcap2.PacketName(pk),
+			Fields:    fields,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func init() {
+	commands.RegisterCommand(&PcapDumpCMD{})
+}
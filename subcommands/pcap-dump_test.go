@@ -0,0 +1,113 @@
+package subcommands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/proxy/pcap2"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+type testFrame struct {
+	pk       packet.Packet
+	toServer bool
+	time     time.Time
+}
+
+// writeTestCapture writes a capture without resource packs that has the frames in it
+func writeTestCapture(t *testing.T, frames []testFrame) string {
+	path := filepath.Join(t.TempDir(), "test.pcap2")
+	zipData := pcap2.EmptyZip()
+	var data bytes.Buffer
+	if err := pcap2.WriteHeader(&data, bytes.NewReader(zipData), int64(len(zipData))); err != nil {
+		t.Fatal(err)
+	}
+	var frame []byte
+	for _, f := range frames {
+		payload := bytes.NewBuffer(nil)
+		header := packet.Header{PacketID: f.pk.ID()}
+		header.Write(payload)
+		f.pk.Marshal(protocol.NewWriter(payload, 0))
+		frame = pcap2.AppendPacket(frame[:0], f.toServer, payload.Bytes(), f.time)
+		data.Write(frame)
+	}
+	if err := os.WriteFile(path, data.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openTestCapture(t *testing.T, path string) *pcap2.Pcap2Reader {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	reader, err := pcap2.NewPcap2Reader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reader
+}
+
+func TestDumpPackets(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	path := writeTestCapture(t, []testFrame{
+		{pk: &packet.SetTime{Time: 1}, time: start},
+		{pk: &packet.Text{TextType: packet.TextTypeChat, Message: "hello"}, toServer: true, time: start.Add(time.Second)},
+		{pk: &packet.SetTime{Time: 2}, time: start.Add(time.Minute)},
+		{pk: &packet.Text{TextType: packet.TextTypeChat, Message: "bye"}, time: start.Add(time.Hour)},
+	})
+
+	type test struct {
+		packets   []string
+		exclude   []string
+		direction string
+		from, to  string
+		expected  []int
+	}
+	var tests = []test{
+		{expected: []int{0, 1, 2, 3}},
+		{packets: []string{"text"}, expected: []int{1, 3}},
+		{exclude: []string{"SetTime"}, direction: "client", expected: []int{3}},
+		{direction: "server", expected: []int{1}},
+		{from: "1s", to: "1m", expected: []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		filter, err := newPacketFilter(tt.packets, tt.exclude, tt.direction, tt.from, tt.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := dumpPackets(context.Background(), openTestCapture(t, path), filter, &out); err != nil {
+			t.Fatal(err)
+		}
+
+		var numbers []int
+		dec := json.NewDecoder(&out)
+		for dec.More() {
+			var dumped dumpedPacket
+			if err := dec.Decode(&dumped); err != nil {
+				t.Fatal(err)
+			}
+			if dumped.Offset != dumped.Time.Sub(start).Milliseconds() {
+				t.Fatalf("packet %d has offset %d at %s", dumped.Number, dumped.Offset, dumped.Time)
+			}
+			if dumped.Number == 1 && (dumped.Name != "Text" || dumped.Direction != "serverbound") {
+				t.Fatalf("packet 1 dumped as %s %s", dumped.Direction, dumped.Name)
+			}
+			numbers = append(numbers, dumped.Number)
+		}
+		if !slices.Equal(numbers, tt.expected) {
+			t.Fatalf("%+v expected: %v\ngot: %v\n", tt, tt.expected, numbers)
+		}
+	}
+}
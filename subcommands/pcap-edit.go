@@ -0,0 +1,318 @@
+package subcommands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/commands"
+	"github.com/bedrock-tool/bedrocktool/utils/proxy/pcap2"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sirupsen/logrus"
+)
+
+type PcapEditSettings struct {
+	File       string   `opt:"Capture File" flag:"file" type:"file,pcap2"`
+	Out        string   `opt:"Out Path" flag:"out" desc:"file to write to, when splitting the piece number is added to the name"`
+	From       string   `opt:"From" flag:"from" desc:"offset into the capture to start at (e.g. 10m)"`
+	To         string   `opt:"To" flag:"to" desc:"offset into the capture to stop at (e.g. 1h30m)"`
+	FromPacket int      `opt:"From Packet" flag:"from-packet" desc:"first packet number to keep"`
+	ToPacket   int      `opt:"To Packet" flag:"to-packet" default:"-1" desc:"last packet number to keep, -1 for all"`
+	Split      string   `opt:"Split" flag:"split" desc:"split the capture into pieces of this length (e.g. 10m)"`
+	Drop       []string `opt:"Drop Packets" flag:"drop" desc:"packets (names or ids) to leave out seperated by comma"`
+	Merge      []string `opt:"Merge" flag:"merge" desc:"captures to append to the end seperated by comma"`
+}
+
+type PcapEditCMD struct{}
+
+func (PcapEditCMD) Name() string {
+	return "pcap-edit"
+}
+
+func (PcapEditCMD) Description() string {
+	return "trim, split, merge or drop packets from captures"
+}
+
+func (PcapEditCMD) Settings() any {
+	return new(PcapEditSettings)
+}
+
+// editSource is one input capture of pcap-edit
+type editSource struct {
+	reader *pcap2.Pcap2Reader
+	// number of the SetLocalPlayerAsInitialised packet that ends the login sequence
+	loginEnd int
+	// added to the packet times so merged captures follow each other
+	shift time.Duration
+}
+
+// mergeGap is the time between the last packet of a capture and the first one of the capture merged after it
+const mergeGap = 50 * time.Millisecond
+
+// rebaseSources moves every source after the first to right after the one before it,
+// the time between the captures would be replayed otherwise
+func rebaseSources(sources []*editSource) {
+	for i := 1; i < len(sources); i++ {
+		prev := sources[i-1]
+		prevIndex := prev.reader.Index()
+		prevEnd := prevIndex[len(prevIndex)-1].Time.Add(prev.shift)
+
+		source := sources[i]
+		index := source.reader.Index()
+		first := index[min(source.loginEnd+1, len(index)-1)].Time
+		source.shift = prevEnd.Add(mergeGap).Sub(first)
+	}
+}
+
+// loginStartGame reads the StartGame packet of the login sequence, nil if there is none
+func loginStartGame(source *editSource) (*packet.StartGame, error) {
+	for i, entry := range source.reader.Index()[:source.loginEnd+1] {
+		if entry.PacketID != packet.IDStartGame {
+			continue
+		}
+		if err := source.reader.Seek(i); err != nil {
+			return nil, err
+		}
+		pk, _, _, err := source.reader.ReadPacket(false)
+		if err != nil {
+			return nil, err
+		}
+		startGame, _ := pk.(*packet.StartGame)
+		return startGame, nil
+	}
+	return nil, nil
+}
+
+// sameLogin checks if two captures joined the same world as the same player
+func sameLogin(a, b *packet.StartGame) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.WorldName == b.WorldName &&
+		a.LevelID == b.LevelID &&
+		a.WorldSeed == b.WorldSeed &&
+		a.EntityUniqueID == b.EntityUniqueID
+}
+
+// checkLogins warns about merged captures that joined another world or as another player,
+// only the login sequence of the first capture is kept so their packets would not match it
+func checkLogins(filenames []string, sources []*editSource) error {
+	first, err := loginStartGame(sources[0])
+	if err != nil {
+		return err
+	}
+	for i, source := range sources[1:] {
+		startGame, err := loginStartGame(source)
+		if err != nil {
+			return err
+		}
+		if !sameLogin(first, startGame) {
+			logrus.Warnf("%s joined another world or player than %s, only the login of %[2]s is kept so its packets might not replay correctly", filenames[i+1], filenames[0])
+		}
+	}
+	return nil
+}
+
+// editPacket is a packet that gets written to the output
+type editPacket struct {
+	source *editSource
+	number int
+	time   time.Time
+}
+
+func openEditSource(filename string) (*editSource, func() error, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := pcap2.NewPcap2Reader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if err = reader.OpenIndex(filename); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	source := &editSource{reader: reader, loginEnd: -1}
+	for i, entry := range reader.Index() {
+		if entry.PacketID == packet.IDSetLocalPlayerAsInitialised {
+			source.loginEnd = i
+			break
+		}
+	}
+	if source.loginEnd < 0 {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s has no complete login sequence", filename)
+	}
+	return source, f.Close, nil
+}
+
+// writeEditPiece writes one output capture,
+// the login sequence of the first source is moved in time to right before the packets
+func writeEditPiece(filename string, zipData []byte, login *editSource, packets []editPacket) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	if err = pcap2.WriteHeader(w, bytes.NewReader(zipData), int64(len(zipData))); err != nil {
+		return err
+	}
+
+	index := login.reader.Index()
+	var shift time.Duration
+	if len(packets) > 0 {
+		shift = max(packets[0].time.Sub(index[login.loginEnd].Time), 0)
+	}
+	for i := 0; i <= login.loginEnd; i++ {
+		frame, err := login.reader.ReadFrame(i)
+		if err != nil {
+			return err
+		}
+		pcap2.SetFrameTime(frame, index[i].Time.Add(shift))
+		if _, err = w.Write(frame); err != nil {
+			return err
+		}
+	}
+
+	for _, pk := range packets {
+		frame, err := pk.source.reader.ReadFrame(pk.number)
+		if err != nil {
+			return err
+		}
+		pcap2.SetFrameTime(frame, pk.time)
+		if _, err = w.Write(frame); err != nil {
+			return err
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	logrus.Infof("Wrote %s (%d packets)", filename, login.loginEnd+1+len(packets))
+	return nil
+}
+
+func (PcapEditCMD) Run(ctx context.Context, settings any) error {
+	editSettings := settings.(*PcapEditSettings)
+	if editSettings.File == "" {
+		return fmt.Errorf("-file must be specified")
+	}
+
+	filter, err := newPacketFilter(nil, editSettings.Drop, "", editSettings.From, editSettings.To)
+	if err != nil {
+		return err
+	}
+	split, err := parseOffset(editSettings.Split, 0)
+	if err != nil {
+		return err
+	}
+
+	filenames := append([]string{editSettings.File}, editSettings.Merge...)
+	var sources []*editSource
+	var readers []*pcap2.Pcap2Reader
+	for _, filename := range filenames {
+		source, closeFile, err := openEditSource(filename)
+		if err != nil {
+			return err
+		}
+		defer closeFile()
+		sources = append(sources, source)
+		readers = append(readers, source.reader)
+	}
+
+	var zipData []byte
+	if len(readers) == 1 {
+		zipSection := readers[0].ZipSection()
+		zipData = make([]byte, zipSection.Size())
+		if _, err = zipSection.ReadAt(zipData, 0); err != nil {
+			return err
+		}
+	} else {
+		if zipData, err = pcap2.MergePackZips(readers...); err != nil {
+			return err
+		}
+		if err = checkLogins(filenames, sources); err != nil {
+			return err
+		}
+		rebaseSources(sources)
+	}
+
+	start, err := sources[0].reader.StartTime()
+	if err != nil {
+		return err
+	}
+
+	// packets are numbered across all captures like they would be after merging
+	var packets []editPacket
+	var number int
+	for _, source := range sources {
+		for i, entry := range source.reader.Index() {
+			number++
+			// every login sequence but the one at the start is left out
+			if i <= source.loginEnd {
+				continue
+			}
+			if number-1 < editSettings.FromPacket || editSettings.ToPacket >= 0 && number-1 > editSettings.ToPacket {
+				continue
+			}
+			t := entry.Time.Add(source.shift)
+			if !filter.match(entry, t.Sub(start)) {
+				continue
+			}
+			packets = append(packets, editPacket{source: source, number: i, time: t})
+		}
+	}
+
+	out := editSettings.Out
+	if out == "" {
+		out = strings.TrimSuffix(editSettings.File, filepath.Ext(editSettings.File)) + "-edited.pcap2"
+	}
+
+	if split <= 0 {
+		return writeEditPiece(out, zipData, sources[0], packets)
+	}
+
+	if len(packets) == 0 {
+		return errors.New("no packets left to split")
+	}
+	base := strings.TrimSuffix(out, filepath.Ext(out))
+	pieceStart := packets[0].time
+	var piece int
+	for len(packets) > 0 {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		end := len(packets)
+		for i, pk := range packets {
+			if pk.time.Sub(pieceStart) >= split {
+				end = i
+				break
+			}
+		}
+		if end > 0 {
+			piece++
+			err = writeEditPiece(fmt.Sprintf("%s-%03d.pcap2", base, piece), zipData, sources[0], packets[:end])
+			if err != nil {
+				return err
+			}
+		}
+		packets = packets[end:]
+		pieceStart = pieceStart.Add(split)
+	}
+	return nil
+}
+
+func init() {
+	commands.RegisterCommand(&PcapEditCMD{})
+}
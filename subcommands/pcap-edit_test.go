@@ -0,0 +1,84 @@
+package subcommands
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func TestPcapEditMerge(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	login := func(t time.Time) []testFrame {
+		return []testFrame{
+			{pk: &packet.StartGame{WorldName: "world"}, time: t},
+			{pk: &packet.SetLocalPlayerAsInitialised{}, toServer: true, time: t.Add(time.Second)},
+		}
+	}
+	first := writeTestCapture(t, append(login(start),
+		testFrame{pk: &packet.SetTime{Time: 1}, time: start.Add(2 * time.Second)},
+		testFrame{pk: &packet.SetTime{Time: 2}, time: start.Add(3 * time.Second)},
+	))
+	// recorded hours later
+	later := start.Add(5 * time.Hour)
+	second := writeTestCapture(t, append(login(later),
+		testFrame{pk: &packet.SetTime{Time: 3}, time: later.Add(2 * time.Second)},
+		testFrame{pk: &packet.SetTime{Time: 4}, time: later.Add(4 * time.Second)},
+	))
+
+	out := filepath.Join(t.TempDir(), "merged.pcap2")
+	err := PcapEditCMD{}.Run(context.Background(), &PcapEditSettings{
+		File:     first,
+		Out:      out,
+		ToPacket: -1,
+		Merge:    []string{second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the login sequence is moved to right before the first packet after it
+	expected := []time.Duration{
+		time.Second, 2 * time.Second,
+		2 * time.Second, 3 * time.Second,
+		3*time.Second + mergeGap, 5*time.Second + mergeGap,
+	}
+	var got []time.Duration
+	r := openTestCapture(t, out)
+	for {
+		_, _, receivedTime, err := r.ReadRaw()
+		if err == net.ErrClosed {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, receivedTime.Sub(start))
+	}
+	if !slices.Equal(got, expected) {
+		t.Fatalf("expected: %v\ngot: %v\n", expected, got)
+	}
+}
+
+func TestSameLogin(t *testing.T) {
+	a := &packet.StartGame{WorldName: "world", LevelID: "abc", WorldSeed: 1, EntityUniqueID: 5}
+	type test struct {
+		b        *packet.StartGame
+		expected bool
+	}
+	var tests = []test{
+		{b: &packet.StartGame{WorldName: "world", LevelID: "abc", WorldSeed: 1, EntityUniqueID: 5, Time: 100}, expected: true},
+		{b: &packet.StartGame{WorldName: "other", LevelID: "abc", WorldSeed: 1, EntityUniqueID: 5}, expected: false},
+		{b: &packet.StartGame{WorldName: "world", LevelID: "abc", WorldSeed: 1, EntityUniqueID: 6}, expected: false},
+		{b: nil, expected: false},
+	}
+	for i, tt := range tests {
+		if got := sameLogin(a, tt.b); got != tt.expected {
+			t.Fatalf("%d expected %v, got %v", i, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package subcommands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/bedrock-tool/bedrocktool/utils"
+	"github.com/bedrock-tool/bedrocktool/utils/commands"
+	"github.com/bedrock-tool/bedrocktool/utils/merge"
+	"github.com/bedrock-tool/bedrocktool/utils/schematic"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/df-mc/dragonfly/server/world/mcdb"
+	"github.com/df-mc/goleveldb/leveldb"
+	"github.com/df-mc/goleveldb/leveldb/opt"
+	"github.com/sirupsen/logrus"
+)
+
+type SchematicSettings struct {
+	WorldPath string `opt:"World Path" flag:"world" desc:"world folder to cut the structure out of"`
+	From      string `opt:"From" flag:"from" desc:"first corner as x,y,z"`
+	To        string `opt:"To" flag:"to" desc:"opposite corner as x,y,z"`
+	Dimension string `opt:"Dimension" flag:"dimension" default:"overworld" desc:"overworld, nether or end"`
+	Entities  bool   `opt:"Entities" flag:"entities" desc:"include entities"`
+	Out       string `opt:"Out Path" flag:"out" default:"structure.mcstructure" desc:"file to write, .mcstructure or .schem"`
+}
+
+type SchematicCMD struct{}
+
+func (SchematicCMD) Name() string {
+	return "schematic"
+}
+
+func (SchematicCMD) Description() string {
+	return "export a region of a downloaded world as .mcstructure or .schem"
+}
+
+func (SchematicCMD) Settings() any {
+	return new(SchematicSettings)
+}
+
+func (SchematicCMD) Run(ctx context.Context, settings any) error {
+	schematicSettings := settings.(*SchematicSettings)
+	if schematicSettings.WorldPath == "" {
+		return fmt.Errorf("missing -world")
+	}
+	from, err := schematic.ParsePos(schematicSettings.From)
+	if err != nil {
+		return fmt.Errorf("-from: %w", err)
+	}
+	to, err := schematic.ParsePos(schematicSettings.To)
+	if err != nil {
+		return fmt.Errorf("-to: %w", err)
+	}
+	var dim world.Dimension
+	switch strings.ToLower(schematicSettings.Dimension) {
+	case "", "overworld":
+		dim = world.Overworld
+	case "nether":
+		dim = world.Nether
+	case "end":
+		dim = world.End
+	default:
+		return fmt.Errorf("unknown dimension %s", schematicSettings.Dimension)
+	}
+
+	blockReg := &merge.BlockRegistry{
+		BlockRegistry: world.DefaultBlockRegistry,
+		Rids:          make(map[uint32]merge.Block),
+	}
+	db, err := mcdb.Config{
+		Log:    slog.Default(),
+		Blocks: blockReg,
+		LDBOptions: &opt.Options{
+			ReadOnly: true,
+		},
+	}.Open(utils.PathData(schematicSettings.WorldPath))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var entities []chunk.Entity
+	structure, err := schematic.Extract(blockReg, from, to, func(pos world.ChunkPos) (*chunk.Chunk, map[cube.Pos]map[string]any, error) {
+		col, err := db.LoadColumn(pos, dim)
+		if errors.Is(err, leveldb.ErrNotFound) {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		entities = append(entities, col.Entities...)
+		blockEntities := make(map[cube.Pos]map[string]any, len(col.BlockEntities))
+		for _, be := range col.BlockEntities {
+			blockEntities[be.Pos] = be.Data
+		}
+		return col.Chunk, blockEntities, nil
+	})
+	if err != nil {
+		return err
+	}
+	if schematicSettings.Entities {
+		for _, ent := range entities {
+			structure.AddEntity(ent.Data)
+		}
+	}
+
+	out := utils.PathData(schematicSettings.Out)
+	if err := structure.WriteFile(out); err != nil {
+		return err
+	}
+	logrus.Infof("Wrote %s to %s", structure, out)
+	return nil
+}
+
+func init() {
+	commands.RegisterCommand(&SchematicCMD{})
+}
@@ -2,10 +2,15 @@ package subcommands
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/bedrock-tool/bedrocktool/handlers/worlds"
+	"github.com/bedrock-tool/bedrocktool/handlers/worlds/worldstate"
 	"github.com/bedrock-tool/bedrocktool/locale"
+	"github.com/bedrock-tool/bedrocktool/utils"
 	"github.com/bedrock-tool/bedrocktool/utils/commands"
 	"github.com/bedrock-tool/bedrocktool/utils/proxy"
 )
@@ -20,7 +25,15 @@ type WorldSettings struct {
 	EntityCulling bool     `opt:"Entity Culling" flag:"entity-culling" desc:"Remove Entities which died or are deleted (experimental)"`
 	ExcludeMobs   []string `opt:"Exclude Mobs" flag:"exclude-mobs" desc:"list of mobs to exclude seperated by comma"`
 	ChunkRadius   int      `opt:"Chunk Radius" flag:"chunk-radius" desc:"the max chunk radius to force"`
-	ScriptPath    string   `opt:"Script Path" flag:"script" desc:"path to script to use" type:"file,js"`
+	Resume        string   `opt:"Resume" flag:"resume" desc:"world folder to add the chunks to instead of starting a new world"`
+	MergePolicy   string   `opt:"Merge Policy" flag:"merge-policy" default:"newer" desc:"when resuming, 'newer' replaces saved chunks that are received again, 'keep' never overwrites them"`
+	Areas         string   `opt:"Areas" flag:"areas" desc:"only keep chunks in these areas seperated by ';', each x1,z1,x2,z2 or x,z,radius in blocks, optionally starting with nether: or end:"`
+	Java          bool     `opt:"Java Export" flag:"java" desc:"also write the world in java edition format"`
+	FlushInterval int      `opt:"Flush Interval" flag:"flush-interval" default:"10" desc:"seconds between writing the downloaded chunks and entities to disk"`
+	History       bool     `opt:"Block History" flag:"history" desc:"record every block change with its time to a .history file next to the world, turns on block updates"`
+	SingleWorld   bool     `opt:"Single World" flag:"single-world" desc:"keep the overworld, nether and end in one world instead of saving a world per dimension visit"`
+	FillHoles     bool     `opt:"Fill Holes" flag:"fill-holes" desc:"request sub chunks that were never received again, within the server's chunk radius"`
+	Coverage      bool     `opt:"Coverage" flag:"coverage" desc:"show how much of the areas, or the map if there are none, is downloaded and the way to the nearest missing chunk"`
 }
 
 type WorldCMD struct{}
@@ -40,15 +53,23 @@ func (WorldCMD) Settings() any {
 func (WorldCMD) Run(ctx context.Context, settings any) error {
 	worldSettings := settings.(*WorldSettings)
 
-	var scriptSource string
-	if worldSettings.ScriptPath != "" {
-		data, err := os.ReadFile(worldSettings.ScriptPath)
-		if err != nil {
-			return err
+	mergePolicy, err := worldstate.ParseMergePolicy(worldSettings.MergePolicy)
+	if err != nil {
+		return err
+	}
+	areas, err := worldstate.ParseAreas(worldSettings.Areas)
+	if err != nil {
+		return err
+	}
+	if worldSettings.Resume != "" {
+		if _, err := os.Stat(filepath.Join(utils.PathData(worldSettings.Resume), "level.dat")); err != nil {
+			return fmt.Errorf("cant resume: %w", err)
 		}
-		scriptSource = string(data)
 	}
 
+	// worlds from a session that crashed
+	worlds.RecoverWorlds()
+
 	p, err := proxy.New(ctx, worldSettings.ProxySettings)
 	if err != nil {
 		return err
@@ -61,9 +82,17 @@ func (WorldCMD) Run(ctx context.Context, settings any) error {
 		SaveImage:       worldSettings.Image,
 		ExcludedMobs:    worldSettings.ExcludeMobs,
 		ChunkRadius:     int32(worldSettings.ChunkRadius),
-		Script:          scriptSource,
-		BlockUpdates:    worldSettings.BlockUpdates,
+		BlockUpdates:    worldSettings.BlockUpdates || worldSettings.History,
 		EntityCulling:   worldSettings.EntityCulling,
+		Resume:          worldSettings.Resume,
+		MergePolicy:     mergePolicy,
+		Areas:           areas,
+		Java:            worldSettings.Java,
+		FlushInterval:   time.Duration(worldSettings.FlushInterval) * time.Second,
+		History:         worldSettings.History,
+		SingleWorld:     worldSettings.SingleWorld,
+		FillHoles:       worldSettings.FillHoles,
+		Coverage:        worldSettings.Coverage,
 		//Players:         true,
 	}))
 
@@ -0,0 +1,364 @@
+// Package api is a local http server that lets other programs run commands and follow their progress.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/ui/messages"
+	"github.com/bedrock-tool/bedrocktool/utils/commands"
+	"github.com/bedrock-tool/bedrocktool/utils/proxy"
+	"github.com/coder/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+type jobState string
+
+const (
+	jobRunning  jobState = "running"
+	jobFinished jobState = "finished"
+	jobFailed   jobState = "failed"
+)
+
+type job struct {
+	ID      int      `json:"id"`
+	Command string   `json:"command"`
+	State   jobState `json:"state"`
+	Error   string   `json:"error,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+type Server struct {
+	Token string
+
+	ctx    context.Context
+	mu     sync.Mutex
+	jobs   []*job
+	events *broadcaster
+}
+
+func New(ctx context.Context, token string) *Server {
+	return &Server{
+		Token:  token,
+		ctx:    ctx,
+		events: newBroadcaster(),
+	}
+}
+
+// ListenAndServe serves the api on address until ctx is done,
+// a token is made up when none is set so other programs on the machine cant use it by accident
+func (s *Server) ListenAndServe(address string) error {
+	if s.Token == "" {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return err
+		}
+		s.Token = hex.EncodeToString(b[:])
+		logrus.Infof("API token: %s", s.Token)
+	}
+	removeListener := messages.AddEventListener(func(event any) {
+		s.events.send(encodeEvent(event))
+	})
+	defer removeListener()
+	hook := &logHook{events: s.events}
+	logrus.AddHook(hook)
+	defer hook.disable()
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Handler:     s.Handler(),
+		BaseContext: func(net.Listener) context.Context { return s.ctx },
+	}
+	go func() {
+		<-s.ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	logrus.Infof("API listening on http://%s", ln.Addr())
+	err = server.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		j.cancel()
+	}
+	s.mu.Unlock()
+	return err
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/commands", s.listCommands)
+	mux.HandleFunc("POST /api/commands/{name}", s.runCommand)
+	mux.HandleFunc("GET /api/jobs", s.listJobs)
+	mux.HandleFunc("DELETE /api/jobs/{id}", s.cancelJob)
+	mux.HandleFunc("GET /api/ingame", s.listIngameCommands)
+	mux.HandleFunc("POST /api/ingame/{name}", s.runIngameCommand)
+	mux.HandleFunc("GET /api/events", s.serveEvents)
+	mux.HandleFunc("GET /api/events/ws", s.serveWebsocket)
+	return s.auth(mux)
+}
+
+// auth checks the token from the Authorization header,
+// or the token query parameter for clients like EventSource that cant set headers.
+// requests from web pages on other origins and posts that arent json are refused
+func (s *Server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Host != r.Host {
+				writeError(w, http.StatusForbidden, errors.New("requests from other origins are not allowed"))
+				return
+			}
+		}
+		if r.Method == http.MethodPost && r.ContentLength != 0 {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("body has to be application/json"))
+				return
+			}
+		}
+		if s.Token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" {
+				token = r.URL.Query().Get("token")
+			}
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+type commandSetting struct {
+	Flag        string `json:"flag"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Default     string `json:"default,omitempty"`
+}
+
+type commandInfo struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Settings    []commandSetting `json:"settings"`
+}
+
+func (s *Server) listCommands(w http.ResponseWriter, r *http.Request) {
+	var infos []commandInfo
+	for name, cmd := range commands.Registered {
+		args, err := commands.ParseArgsType(reflect.ValueOf(cmd.Settings()), nil, nil)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		info := commandInfo{Name: name, Description: cmd.Description(), Settings: []commandSetting{}}
+		for _, arg := range args {
+			info.Settings = append(info.Settings, commandSetting{
+				Flag:        arg.Flag,
+				Name:        arg.Name,
+				Description: arg.Desc,
+				Type:        arg.Type,
+				Default:     arg.Default,
+			})
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b commandInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) runCommand(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	cmd, ok := commands.Registered[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown command %s", name))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	settings, err := commands.ParseJSON(cmd, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.mu.Lock()
+	j := &job{ID: len(s.jobs) + 1, Command: name, State: jobRunning, cancel: cancel}
+	s.jobs = append(s.jobs, j)
+	s.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := cmd.Run(ctx, settings)
+		s.mu.Lock()
+		if err != nil && !errors.Is(err, context.Canceled) {
+			j.State = jobFailed
+			j.Error = err.Error()
+		} else {
+			j.State = jobFinished
+		}
+		s.mu.Unlock()
+		s.events.send(encodeEvent(&EventJobFinished{ID: j.ID, Command: j.Command, State: j.State, Error: j.Error}))
+	}()
+
+	writeJSON(w, http.StatusAccepted, map[string]int{"id": j.ID})
+}
+
+// EventJobFinished is sent when a command started through the api ends
+type EventJobFinished struct {
+	ID      int
+	Command string
+	State   jobState
+	Error   string
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, *j)
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id < 1 || id > len(s.jobs) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown job %d", id))
+		return
+	}
+	s.jobs[id-1].cancel()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ingameCommandInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (s *Server) listIngameCommands(w http.ResponseWriter, r *http.Request) {
+	infos := []ingameCommandInfo{}
+	for _, cmd := range proxy.IngameCommands() {
+		infos = append(infos, ingameCommandInfo{Name: cmd.Name, Description: cmd.Description})
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) runIngameCommand(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Args []string `json:"args"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	name := r.PathValue("name")
+	found, ok := proxy.ExecIngameCommand(name, body.Args)
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no running session has the command %s", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": ok})
+}
+
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	events, unsubscribe := s.events.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-events:
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+	events, unsubscribe := s.events.subscribe()
+	defer unsubscribe()
+
+	// the client doesnt send anything, reading handles pings and closing
+	ctx := conn.CloseRead(r.Context())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-events:
+			if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+				return
+			}
+		}
+	}
+}
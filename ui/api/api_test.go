@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/commands"
+)
+
+type testCommandSettings struct {
+	Name string `opt:"Name" flag:"name"`
+	Fail bool   `opt:"Fail" flag:"fail"`
+}
+
+type testCommand struct{}
+
+func (testCommand) Name() string        { return "apitest" }
+func (testCommand) Description() string { return "" }
+func (testCommand) Settings() any       { return new(testCommandSettings) }
+func (testCommand) Run(ctx context.Context, settings any) error {
+	if settings.(*testCommandSettings).Fail {
+		return errors.New("failed on purpose")
+	}
+	return nil
+}
+
+func init() {
+	commands.RegisterCommand(testCommand{})
+}
+
+func testRequest(t *testing.T, h http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuth(t *testing.T) {
+	s := New(t.Context(), "secret")
+	h := s.Handler()
+	bearer := map[string]string{"Authorization": "Bearer secret"}
+
+	type test struct {
+		method, target, body string
+		header               map[string]string
+		status               int
+	}
+	var tests = []test{
+		{method: "GET", target: "/api/jobs", status: http.StatusUnauthorized},
+		{method: "GET", target: "/api/jobs", header: map[string]string{"Authorization": "Bearer wrong"}, status: http.StatusUnauthorized},
+		{method: "GET", target: "/api/jobs", header: bearer, status: http.StatusOK},
+		{method: "GET", target: "/api/jobs?token=secret", status: http.StatusOK},
+		{method: "GET", target: "/api/jobs", header: map[string]string{"Authorization": "Bearer secret", "Origin": "http://evil.example"}, status: http.StatusForbidden},
+		{method: "POST", target: "/api/commands/apitest", body: "{}", header: map[string]string{"Authorization": "Bearer secret", "Content-Type": "text/plain"}, status: http.StatusUnsupportedMediaType},
+		{method: "POST", target: "/api/commands/missing", header: bearer, status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rec := testRequest(t, h, tt.method, tt.target, tt.body, tt.header); rec.Code != tt.status {
+			t.Errorf("%s %s %v: expected %d, got %d %s", tt.method, tt.target, tt.header, tt.status, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	s := New(t.Context(), "")
+	h := s.Handler()
+	events, unsubscribe := s.events.subscribe()
+	defer unsubscribe()
+	header := map[string]string{"Content-Type": "application/json"}
+
+	if rec := testRequest(t, h, "POST", "/api/commands/apitest", `{"unknown": 1}`, header); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown setting: expected 400, got %d", rec.Code)
+	}
+
+	for i, body := range []string{`{"name": "a"}`, `{"fail": true}`} {
+		rec := testRequest(t, h, "POST", "/api/commands/apitest", body, header)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d %s", rec.Code, rec.Body)
+		}
+		var started struct{ ID int }
+		if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil || started.ID != i+1 {
+			t.Fatalf("expected job %d, got %s", i+1, rec.Body)
+		}
+		select {
+		case data := <-events:
+			var e encodedEvent
+			if err := json.Unmarshal(data, &e); err != nil {
+				t.Fatal(err)
+			}
+			if e.Type != "JobFinished" {
+				t.Fatalf("expected JobFinished, got %s", data)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("job didnt finish")
+		}
+	}
+
+	rec := testRequest(t, h, "GET", "/api/jobs", "", nil)
+	var jobs []job
+	if err := json.Unmarshal(rec.Body.Bytes(), &jobs); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].State != jobFinished || jobs[1].State != jobFailed || jobs[1].Error != "failed on purpose" {
+		t.Fatalf("unexpected jobs %s", rec.Body)
+	}
+
+	if rec := testRequest(t, h, "DELETE", "/api/jobs/3", "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("cancelling unknown job: expected 404, got %d", rec.Code)
+	}
+	if rec := testRequest(t, h, "POST", "/api/ingame/apitest", "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("ingame command without a session: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestEncodeEvent(t *testing.T) {
+	type EventTest struct {
+		Name  string
+		Err   error
+		Other any
+	}
+	var e encodedEvent
+	if err := json.Unmarshal(encodeEvent(&EventTest{Name: "a", Err: errors.New("broken")}), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != "Test" || e.Data["Name"] != "a" || e.Data["Err"] != "broken" || e.Data["Other"] != nil {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if encodeEvent(1) != nil {
+		t.Fatal("non struct events should be skipped")
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"image"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bedrock-tool/bedrocktool/ui/messages"
+	"github.com/sirupsen/logrus"
+)
+
+// broadcaster hands encoded events to every subscriber,
+// slow subscribers miss events instead of blocking the sender
+type broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan []byte]struct{}
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subscribers: make(map[chan []byte]struct{})}
+}
+
+func (b *broadcaster) subscribe() (<-chan []byte, func()) {
+	ch := make(chan []byte, 256)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}
+}
+
+func (b *broadcaster) send(data []byte) {
+	if data == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+type encodedEvent struct {
+	Type string         `json:"type"`
+	Data map[string]any `json:"data"`
+}
+
+// encodeEvent turns an event into json,
+// errors become their message and images are left out
+func encodeEvent(event any) []byte {
+	v := reflect.Indirect(reflect.ValueOf(event))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	e := encodedEvent{
+		Type: strings.TrimPrefix(t.Name(), "Event"),
+		Data: make(map[string]any),
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i).Interface()
+		switch value := value.(type) {
+		case error:
+			e.Data[field.Name] = value.Error()
+			continue
+		case *image.RGBA, image.RGBA:
+			continue
+		case []messages.MapTile:
+			positions := make([][2]int32, 0, len(value))
+			for _, tile := range value {
+				positions = append(positions, [2]int32(tile.Pos))
+			}
+			e.Data[field.Name] = positions
+			continue
+		}
+		if field.Type.Kind() == reflect.Interface && v.Field(i).IsNil() {
+			e.Data[field.Name] = nil
+			continue
+		}
+		e.Data[field.Name] = value
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// logHook sends log lines as Log events
+type logHook struct {
+	events   *broadcaster
+	disabled atomic.Bool
+}
+
+func (h *logHook) Levels() []logrus.Level {
+	return []logrus.Level{
+		logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel,
+		logrus.WarnLevel, logrus.InfoLevel,
+	}
+}
+
+func (h *logHook) Fire(entry *logrus.Entry) error {
+	if h.disabled.Load() {
+		return nil
+	}
+	data, err := json.Marshal(encodedEvent{
+		Type: "Log",
+		Data: map[string]any{
+			"Level":   entry.Level.String(),
+			"Message": entry.Message,
+			"Time":    entry.Time,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	h.events.send(data)
+	return nil
+}
+
+// disable stops the hook, logrus has no way to remove a single hook
+func (h *logHook) disable() {
+	h.disabled.Store(true)
+}
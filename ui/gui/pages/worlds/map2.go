@@ -1,7 +1,9 @@
 package worlds
 
 import (
+	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
 	"math"
 	"sync"
@@ -9,10 +11,12 @@ import (
 	"gioui.org/f32"
 	"gioui.org/io/event"
 	"gioui.org/io/pointer"
+	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
 	"gioui.org/widget"
+	"gioui.org/widget/material"
 	"github.com/bedrock-tool/bedrocktool/ui/messages"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
@@ -36,6 +40,7 @@ type Map2 struct {
 
 	tileImages map[image.Point]*image.RGBA
 	imageOps   map[image.Point]paint.ImageOp
+	coverage   *messages.EventCoverage
 	l          sync.Mutex
 }
 
@@ -127,9 +132,45 @@ func (m *Map2) Layout(gtx C) D {
 		aff.Pop()
 	}
 
+	// outline the nearest missing chunk
+	if m.coverage != nil && m.coverage.HasTarget {
+		pt := f32.Pt(
+			float32(float64(m.coverage.Target.X()*16)*m.mapInput.scaleFactor),
+			float32(float64(m.coverage.Target.Z()*16)*m.mapInput.scaleFactor),
+		)
+		aff := op.Affine(m.mapInput.transform.Offset(m.mapInput.center).Offset(pt)).Push(gtx.Ops)
+		paint.FillShape(gtx.Ops, color.NRGBA{R: 0xff, A: 0xff}, clip.Stroke{
+			Path:  clip.Rect{Max: image.Pt(16, 16)}.Path(),
+			Width: 2,
+		}.Op())
+		aff.Pop()
+	}
+
 	return D{Size: gtx.Constraints.Max}
 }
 
+func (m *Map2) SetCoverage(coverage *messages.EventCoverage) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if coverage.Total == 0 {
+		m.coverage = nil
+		return
+	}
+	m.coverage = coverage
+}
+
+// LayoutCoverage shows how much of the target area is downloaded
+func (m *Map2) LayoutCoverage(gtx C, th *material.Theme) D {
+	m.l.Lock()
+	coverage := m.coverage
+	m.l.Unlock()
+	if coverage == nil {
+		return D{}
+	}
+	text := fmt.Sprintf("Coverage %d%% (%d/%d chunks)", coverage.Received*100/coverage.Total, coverage.Received, coverage.Total)
+	return layout.UniformInset(8).Layout(gtx, material.Label(th, th.TextSize, text).Layout)
+}
+
 func chunkPosToTilePos(cp protocol.ChunkPos) (tile image.Point, offset image.Point) {
 	blockX := int(cp.X()) * 16
 	blockY := int(cp.Z()) * 16
@@ -176,6 +217,7 @@ func (m *Map2) AddTiles(tiles []messages.MapTile) {
 func (m *Map2) Reset() {
 	m.l.Lock()
 	defer m.l.Unlock()
+	m.coverage = nil
 	m.tileImages = make(map[image.Point]*image.RGBA)
 	m.imageOps = make(map[image.Point]paint.ImageOp)
 }
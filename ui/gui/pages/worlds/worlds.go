@@ -176,6 +176,14 @@ func (p *Page) Layout(gtx C, th *material.Theme) D {
 				return D{}
 			}
 		}),
+		layout.Expanded(func(gtx C) D {
+			if p.State != messages.UIStateMain {
+				return D{}
+			}
+			return layout.SE.Layout(gtx, func(gtx C) D {
+				return p.worldMap.LayoutCoverage(gtx, th)
+			})
+		}),
 		layout.Stacked(func(gtx C) D {
 			p.finishedWorldsMu.Lock()
 			defer p.finishedWorldsMu.Unlock()
@@ -203,6 +211,9 @@ func (u *Page) HandleEvent(event any) error {
 	case *messages.EventResetMap:
 		u.worldMap.Reset()
 
+	case *messages.EventCoverage:
+		u.worldMap.SetCoverage(event)
+
 	case *messages.EventPlayerPosition:
 		u.worldMap.mapInput.playerPosition = event.Position
 
@@ -2,6 +2,7 @@ package messages
 
 import (
 	"image"
+	"sync"
 
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
@@ -115,6 +116,13 @@ type EventMapTiles struct {
 
 type EventResetMap struct{}
 
+// EventCoverage is how much of the target area is downloaded, Total is 0 when the planner is off
+type EventCoverage struct {
+	Received, Total int
+	Target          protocol.ChunkPos
+	HasTarget       bool
+}
+
 type EventPlayerPosition struct {
 	Position mgl32.Vec3
 }
@@ -136,8 +144,35 @@ func SetEventHandler(f func(event any) error) {
 	eventHandler = f
 }
 
+var (
+	listenersMu    sync.Mutex
+	listeners      = map[int]func(event any){}
+	nextListenerID int
+)
+
+// AddEventListener makes f get called with every event next to the ui,
+// the returned func removes it again
+func AddEventListener(f func(event any)) (remove func()) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+	id := nextListenerID
+	nextListenerID++
+	listeners[id] = f
+	return func() {
+		listenersMu.Lock()
+		defer listenersMu.Unlock()
+		delete(listeners, id)
+	}
+}
+
 func SendEvent(event any) {
 	//fmt.Printf("event %s\n", reflect.TypeOf(event).String())
+	listenersMu.Lock()
+	for _, f := range listeners {
+		f(event)
+	}
+	listenersMu.Unlock()
+
 	err := eventHandler(event)
 	if err != nil {
 		logrus.Errorf("event handler errored %s", err)
@@ -0,0 +1,372 @@
+package anvil
+
+import (
+	_ "embed"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// javaState is a block in the java palette format
+type javaState struct {
+	Name       string
+	Properties map[string]string
+}
+
+func (s javaState) key() string {
+	if len(s.Properties) == 0 {
+		return s.Name
+	}
+	var parts []string
+	for _, k := range slices.Sorted(maps.Keys(s.Properties)) {
+		parts = append(parts, k+"="+s.Properties[k])
+	}
+	return s.Name + "[" + strings.Join(parts, ",") + "]"
+}
+
+func (s javaState) nbt() map[string]any {
+	tag := map[string]any{"Name": s.Name}
+	if len(s.Properties) > 0 {
+		properties := make(map[string]any, len(s.Properties))
+		for k, v := range s.Properties {
+			properties[k] = v
+		}
+		tag["Properties"] = properties
+	}
+	return tag
+}
+
+var airState = javaState{Name: "minecraft:air"}
+
+//go:embed java_blocks.txt
+var javaBlocksList string
+
+// javaBlocks are the names of all java blocks, bedrock blocks that end up as something else are dropped
+var javaBlocks = sync.OnceValue(func() map[string]bool {
+	blocks := make(map[string]bool)
+	for _, name := range strings.Fields(javaBlocksList) {
+		blocks[name] = true
+	}
+	return blocks
+})
+
+// blocks that have a different name on java, most were flattened to the java names already
+var renamedBlocks = map[string]string{
+	"minecraft:snow":                             "minecraft:snow_block",
+	"minecraft:snow_layer":                       "minecraft:snow",
+	"minecraft:flowing_water":                    "minecraft:water",
+	"minecraft:flowing_lava":                     "minecraft:lava",
+	"minecraft:grass":                            "minecraft:grass_block",
+	"minecraft:grass_path":                       "minecraft:dirt_path",
+	"minecraft:tallgrass":                        "minecraft:short_grass",
+	"minecraft:deadbush":                         "minecraft:dead_bush",
+	"minecraft:web":                              "minecraft:cobweb",
+	"minecraft:waterlily":                        "minecraft:lily_pad",
+	"minecraft:yellow_flower":                    "minecraft:dandelion",
+	"minecraft:reeds":                            "minecraft:sugar_cane",
+	"minecraft:lit_pumpkin":                      "minecraft:jack_o_lantern",
+	"minecraft:melon_block":                      "minecraft:melon",
+	"minecraft:quartz_ore":                       "minecraft:nether_quartz_ore",
+	"minecraft:brick_block":                      "minecraft:bricks",
+	"minecraft:nether_brick":                     "minecraft:nether_bricks",
+	"minecraft:red_nether_brick":                 "minecraft:red_nether_bricks",
+	"minecraft:end_bricks":                       "minecraft:end_stone_bricks",
+	"minecraft:stonebrick":                       "minecraft:stone_bricks",
+	"minecraft:mob_spawner":                      "minecraft:spawner",
+	"minecraft:noteblock":                        "minecraft:note_block",
+	"minecraft:golden_rail":                      "minecraft:powered_rail",
+	"minecraft:slime":                            "minecraft:slime_block",
+	"minecraft:magma":                            "minecraft:magma_block",
+	"minecraft:portal":                           "minecraft:nether_portal",
+	"minecraft:invisible_bedrock":                "minecraft:barrier",
+	"minecraft:border_block":                     "minecraft:barrier",
+	"minecraft:allow":                            "minecraft:barrier",
+	"minecraft:deny":                             "minecraft:barrier",
+	"minecraft:stonecutter_block":                "minecraft:stonecutter",
+	"minecraft:stone_stairs":                     "minecraft:cobblestone_stairs",
+	"minecraft:normal_stone_stairs":              "minecraft:stone_stairs",
+	"minecraft:wooden_door":                      "minecraft:oak_door",
+	"minecraft:trapdoor":                         "minecraft:oak_trapdoor",
+	"minecraft:fence_gate":                       "minecraft:oak_fence_gate",
+	"minecraft:wooden_button":                    "minecraft:oak_button",
+	"minecraft:wooden_pressure_plate":            "minecraft:oak_pressure_plate",
+	"minecraft:standing_sign":                    "minecraft:oak_sign",
+	"minecraft:wall_sign":                        "minecraft:oak_wall_sign",
+	"minecraft:darkoak_standing_sign":            "minecraft:dark_oak_sign",
+	"minecraft:darkoak_wall_sign":                "minecraft:dark_oak_wall_sign",
+	"minecraft:powered_repeater":                 "minecraft:repeater",
+	"minecraft:unpowered_repeater":               "minecraft:repeater",
+	"minecraft:powered_comparator":               "minecraft:comparator",
+	"minecraft:unpowered_comparator":             "minecraft:comparator",
+	"minecraft:unlit_redstone_torch":             "minecraft:redstone_torch",
+	"minecraft:daylight_detector_inverted":       "minecraft:daylight_detector",
+	"minecraft:bed":                              "minecraft:white_bed",
+	"minecraft:beetroot":                         "minecraft:beetroots",
+	"minecraft:standing_banner":                  "minecraft:white_banner",
+	"minecraft:wall_banner":                      "minecraft:white_wall_banner",
+	"minecraft:skull":                            "minecraft:skeleton_skull",
+	"minecraft:frame":                            "minecraft:air",
+	"minecraft:glow_frame":                       "minecraft:air",
+	"minecraft:item_frame":                       "minecraft:air",
+	"minecraft:client_request_placeholder_block": "minecraft:air",
+}
+
+// blocks that are a lit_ version on bedrock and a lit property on java
+var litBlocks = map[string]bool{
+	"minecraft:furnace":                true,
+	"minecraft:smoker":                 true,
+	"minecraft:blast_furnace":          true,
+	"minecraft:redstone_lamp":          true,
+	"minecraft:redstone_ore":           true,
+	"minecraft:deepslate_redstone_ore": true,
+}
+
+var (
+	// direction property of most blocks
+	directionFacing = []string{"south", "west", "north", "east"}
+	// facing_direction property
+	facingDirection = []string{"down", "up", "north", "south", "west", "east"}
+	// direction property of doors
+	doorFacing = []string{"east", "south", "west", "north"}
+	// direction property of trapdoors
+	trapdoorFacing = []string{"east", "west", "south", "north"}
+	// weirdo_direction property of stairs
+	stairsFacing = []string{"east", "west", "south", "north"}
+
+	torchWallNames = map[string]string{
+		"minecraft:torch":          "minecraft:wall_torch",
+		"minecraft:soul_torch":     "minecraft:soul_wall_torch",
+		"minecraft:redstone_torch": "minecraft:redstone_wall_torch",
+	}
+)
+
+// dyeColors in the java order, bedrock beds use the same order and banners the reverse
+var dyeColors = []string{
+	"white", "orange", "magenta", "light_blue", "yellow", "lime", "pink", "gray",
+	"light_gray", "cyan", "purple", "blue", "brown", "green", "red", "black",
+}
+
+func propertyInt(v any) (int, bool) {
+	switch v := v.(type) {
+	case int32:
+		return int(v), true
+	case uint8:
+		return int(v), true
+	case int16:
+		return int(v), true
+	case int64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func propertyBool(v any) bool {
+	i, ok := propertyInt(v)
+	if ok {
+		return i != 0
+	}
+	b, _ := v.(bool)
+	return b
+}
+
+func index(values []string, i int) string {
+	if i < 0 || i >= len(values) {
+		return values[0]
+	}
+	return values[i]
+}
+
+// convertBlock maps a bedrock block state to the closest java one
+func convertBlock(name string, properties map[string]any) javaState {
+	if !strings.HasPrefix(name, "minecraft:") {
+		// custom blocks dont exist on java
+		return airState
+	}
+
+	out := map[string]string{}
+	if lit, ok := strings.CutPrefix(name, "minecraft:lit_"); ok && litBlocks["minecraft:"+lit] {
+		name = "minecraft:" + lit
+		out["lit"] = "true"
+	} else if litBlocks[name] {
+		out["lit"] = "false"
+	}
+	switch name {
+	case "minecraft:unlit_redstone_torch":
+		out["lit"] = "false"
+	case "minecraft:powered_repeater", "minecraft:powered_comparator":
+		out["powered"] = "true"
+	}
+	if renamed, ok := renamedBlocks[name]; ok {
+		name = renamed
+	}
+	if wood, ok := strings.CutSuffix(name, "_standing_sign"); ok {
+		name = wood + "_sign"
+	}
+	if slab, ok := strings.CutSuffix(name, "_double_slab"); ok {
+		name = slab + "_slab"
+		out["type"] = "double"
+	}
+
+	for key, value := range properties {
+		i, _ := propertyInt(value)
+		switch key {
+		case "pillar_axis":
+			out["axis"] = fmt.Sprint(value)
+		case "minecraft:cardinal_direction", "minecraft:facing_direction":
+			out["facing"] = fmt.Sprint(value)
+		case "facing_direction":
+			out["facing"] = index(facingDirection, i)
+		case "direction":
+			switch {
+			case strings.HasSuffix(name, "_door"):
+				out["facing"] = index(doorFacing, i)
+			case strings.HasSuffix(name, "_trapdoor"):
+				out["facing"] = index(trapdoorFacing, i)
+			default:
+				out["facing"] = index(directionFacing, i)
+			}
+		case "weirdo_direction":
+			out["facing"] = index(stairsFacing, i)
+		case "upside_down_bit":
+			if strings.HasSuffix(name, "_stairs") || strings.HasSuffix(name, "_trapdoor") {
+				out["half"] = map[bool]string{true: "top", false: "bottom"}[propertyBool(value)]
+			}
+		case "minecraft:vertical_half":
+			if out["type"] == "" {
+				out["type"] = fmt.Sprint(value)
+			}
+		case "top_slot_bit":
+			if out["type"] == "" {
+				out["type"] = map[bool]string{true: "top", false: "bottom"}[propertyBool(value)]
+			}
+		case "upper_block_bit":
+			out["half"] = map[bool]string{true: "upper", false: "lower"}[propertyBool(value)]
+		case "head_piece_bit":
+			out["part"] = map[bool]string{true: "head", false: "foot"}[propertyBool(value)]
+		case "door_hinge_bit":
+			out["hinge"] = map[bool]string{true: "right", false: "left"}[propertyBool(value)]
+		case "open_bit":
+			out["open"] = strconv.FormatBool(propertyBool(value))
+		case "occupied_bit":
+			out["occupied"] = strconv.FormatBool(propertyBool(value))
+		case "in_wall_bit":
+			out["in_wall"] = strconv.FormatBool(propertyBool(value))
+		case "attached_bit":
+			out["attached"] = strconv.FormatBool(propertyBool(value))
+		case "hanging":
+			out["hanging"] = strconv.FormatBool(propertyBool(value))
+		case "button_pressed_bit", "powered_bit":
+			out["powered"] = strconv.FormatBool(propertyBool(value))
+		case "end_portal_eye_bit":
+			out["eye"] = strconv.FormatBool(propertyBool(value))
+		case "extinguished":
+			out["lit"] = strconv.FormatBool(!propertyBool(value))
+		case "growth":
+			if name == "minecraft:beetroots" {
+				i /= 2
+			}
+			out["age"] = strconv.Itoa(i)
+		case "age":
+			out["age"] = strconv.Itoa(i)
+		case "liquid_depth":
+			out["level"] = strconv.Itoa(i)
+		case "redstone_signal":
+			out["power"] = strconv.Itoa(i)
+		case "moisturized_amount":
+			out["moisture"] = strconv.Itoa(i)
+		case "height":
+			if name == "minecraft:snow" {
+				out["layers"] = strconv.Itoa(i + 1)
+			}
+		case "ground_sign_direction":
+			out["rotation"] = strconv.Itoa(i)
+		case "vine_direction_bits":
+			out["south"] = strconv.FormatBool(i&1 != 0)
+			out["west"] = strconv.FormatBool(i&2 != 0)
+			out["north"] = strconv.FormatBool(i&4 != 0)
+			out["east"] = strconv.FormatBool(i&8 != 0)
+		case "torch_facing_direction":
+			if wall, ok := torchWallNames[name]; ok && value != "top" && value != "unknown" {
+				name = wall
+				out["facing"] = fmt.Sprint(value)
+			}
+		case "lever_direction":
+			direction := fmt.Sprint(value)
+			switch {
+			case strings.HasPrefix(direction, "up_"):
+				out["face"] = "floor"
+			case strings.HasPrefix(direction, "down_"):
+				out["face"] = "ceiling"
+			default:
+				out["face"] = "wall"
+				out["facing"] = direction
+			}
+			if out["face"] != "wall" {
+				out["facing"] = map[bool]string{true: "north", false: "east"}[strings.HasSuffix(direction, "north_south")]
+			}
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(name, "_button"):
+		// the facing of buttons is where they point, java splits it into face and facing
+		switch out["facing"] {
+		case "up":
+			out["face"], out["facing"] = "floor", "north"
+		case "down":
+			out["face"], out["facing"] = "ceiling", "north"
+		default:
+			out["face"] = "wall"
+		}
+	case strings.HasSuffix(name, "_leaves"):
+		// nothing is simulated in the download so they would decay
+		out["persistent"] = "true"
+	case strings.HasSuffix(name, "_skull") || strings.HasSuffix(name, "_head"):
+		if facing := out["facing"]; facing != "" && facing != "up" && facing != "down" {
+			name = strings.Replace(name, "_skull", "_wall_skull", 1)
+			name = strings.Replace(name, "_head", "_wall_head", 1)
+		} else {
+			delete(out, "facing")
+		}
+	}
+	if !javaBlocks()[name] {
+		return airState
+	}
+	return javaState{Name: name, Properties: out}
+}
+
+// colored returns if the java block name depends on a color in the block entity
+func (s javaState) colored() bool {
+	return s.Name == "minecraft:white_bed" || s.Name == "minecraft:white_banner" || s.Name == "minecraft:white_wall_banner"
+}
+
+// withColor uses the color from the bedrock block entity of beds and banners
+func (s javaState) withColor(blockEntity map[string]any) javaState {
+	var color string
+	if s.Name == "minecraft:white_bed" {
+		i, _ := propertyInt(blockEntity["color"])
+		color = index(dyeColors, i)
+	} else {
+		i, _ := propertyInt(blockEntity["Base"])
+		color = index(dyeColors, 15-i)
+	}
+	s.Name = strings.Replace(s.Name, "white", color, 1)
+	return s
+}
+
+// JavaBlockState returns the java block state string of a bedrock block, like minecraft:oak_stairs[facing=east,...]
+// blockEntity is used for the color of beds and banners and can be nil
+func JavaBlockState(name string, properties map[string]any, blockEntity map[string]any, waterlogged bool) string {
+	s := convertBlock(name, properties)
+	if s.colored() && blockEntity != nil {
+		s = s.withColor(blockEntity)
+	}
+	if waterlogged && s.Name != "minecraft:air" {
+		s.Properties = maps.Clone(s.Properties)
+		s.Properties["waterlogged"] = "true"
+	}
+	return s.key()
+}
@@ -0,0 +1,42 @@
+package anvil
+
+import (
+	"testing"
+
+	"github.com/df-mc/dragonfly/server/world"
+)
+
+func TestConvertBlockUnknown(t *testing.T) {
+	for _, name := range renamedBlocks {
+		if !javaBlocks()[name] {
+			t.Fatalf("%s is renamed to a block java doesnt have", name)
+		}
+	}
+	if s := convertBlock("minecraft:stone", nil); s.Name != "minecraft:stone" {
+		t.Fatalf("stone became %s", s.Name)
+	}
+	// bedrock only blocks that arent renamed cant be kept
+	for _, name := range []string{"minecraft:camera", "minecraft:element_1", "custom:block"} {
+		if s := convertBlock(name, nil); s.Name != airState.Name {
+			t.Fatalf("%s became %s", name, s.key())
+		}
+	}
+}
+
+func TestConverterDropped(t *testing.T) {
+	c := &converter{
+		blocks:  world.DefaultBlockRegistry,
+		states:  make(map[uint32]javaState),
+		dropped: make(map[string]int),
+	}
+	for _, name := range []string{"minecraft:stone", "minecraft:camera"} {
+		rid, ok := world.DefaultBlockRegistry.StateToRuntimeID(name, map[string]any{})
+		if !ok {
+			t.Fatalf("no runtime id for %s", name)
+		}
+		c.state(rid)
+	}
+	if len(c.dropped) != 1 || c.dropped["minecraft:camera"] != 1 {
+		t.Fatalf("expected only camera to be dropped, got %v", c.dropped)
+	}
+}
@@ -0,0 +1,338 @@
+package anvil
+
+import (
+	"compress/gzip"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/merge"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/df-mc/dragonfly/server/world/mcdb"
+	"github.com/df-mc/goleveldb/leveldb/opt"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"github.com/sirupsen/logrus"
+)
+
+// DataVersion of java 1.20.4, newer versions upgrade worlds from it when they are opened
+const DataVersion = 3700
+
+// longArray makes an nbt long array, slices are written as lists
+func longArray(values []int64) any {
+	arr := reflect.New(reflect.ArrayOf(len(values), reflect.TypeFor[int64]())).Elem()
+	reflect.Copy(arr, reflect.ValueOf(values))
+	return arr.Interface()
+}
+
+// packPalette packs palette indices the way java does since 1.16, entries dont span longs
+func packPalette(indices []int, paletteSize int) any {
+	bitsPerEntry := max(4, bits.Len(uint(paletteSize-1)))
+	perLong := 64 / bitsPerEntry
+	data := make([]int64, (len(indices)+perLong-1)/perLong)
+	for i, v := range indices {
+		data[i/perLong] |= int64(v) << ((i % perLong) * bitsPerEntry)
+	}
+	return longArray(data)
+}
+
+type converter struct {
+	blocks world.BlockRegistry
+	states map[uint32]javaState
+	// blocks that dont exist on java
+	dropped map[string]int
+}
+
+func (c *converter) state(rid uint32) javaState {
+	if s, ok := c.states[rid]; ok {
+		return s
+	}
+	name, properties, found := c.blocks.RuntimeIDToState(rid)
+	s := airState
+	if found {
+		s = convertBlock(name, properties)
+		if s.Name == "minecraft:air" && name != "minecraft:air" {
+			c.dropped[name]++
+		}
+	}
+	c.states[rid] = s
+	return s
+}
+
+func isWater(name string) bool {
+	return name == "minecraft:water" || name == "minecraft:flowing_water"
+}
+
+// convertChunk makes the java nbt of a chunk column
+func (c *converter) convertChunk(pos world.ChunkPos, col *chunk.Column) map[string]any {
+	ch := col.Chunk
+	blockEntities := make(map[cube.Pos]map[string]any)
+	for _, be := range col.BlockEntities {
+		blockEntities[be.Pos] = be.Data
+	}
+
+	var sections []any
+	for i, sub := range ch.Sub() {
+		if sub.Empty() {
+			continue
+		}
+		baseY := ch.SubY(int16(i))
+
+		var palette []any
+		paletteIndex := make(map[string]int)
+		indices := make([]int, 4096)
+		for y := range int16(16) {
+			for z := range uint8(16) {
+				for x := range uint8(16) {
+					s := c.state(ch.Block(x, baseY+y, z, 0))
+					if s.colored() {
+						if be, ok := blockEntities[cube.Pos{int(pos[0])*16 + int(x), int(baseY + y), int(pos[1])*16 + int(z)}]; ok {
+							s = s.withColor(be)
+						}
+					}
+					if len(sub.Layers()) > 1 && s.Name != "minecraft:air" {
+						name, _, _ := c.blocks.RuntimeIDToState(ch.Block(x, baseY+y, z, 1))
+						if isWater(name) {
+							s.Properties = maps.Clone(s.Properties)
+							s.Properties["waterlogged"] = "true"
+						}
+					}
+					key := s.key()
+					idx, ok := paletteIndex[key]
+					if !ok {
+						idx = len(palette)
+						paletteIndex[key] = idx
+						palette = append(palette, s.nbt())
+					}
+					indices[int(y)*256+int(z)*16+int(x)] = idx
+				}
+			}
+		}
+
+		blockStates := map[string]any{"palette": palette}
+		if len(palette) > 1 {
+			blockStates["data"] = packPalette(indices, len(palette))
+		}
+		sections = append(sections, map[string]any{
+			"Y":            uint8(int8(baseY >> 4)),
+			"block_states": blockStates,
+			"biomes": map[string]any{
+				"palette": []any{"minecraft:plains"},
+			},
+		})
+	}
+
+	javaBlockEntities := []any{}
+	for pos, data := range blockEntities {
+		if be, ok := convertBlockEntity(data, int32(pos[0]), int32(pos[1]), int32(pos[2])); ok {
+			javaBlockEntities = append(javaBlockEntities, be)
+		}
+	}
+
+	return map[string]any{
+		"DataVersion":    int32(DataVersion),
+		"xPos":           pos[0],
+		"zPos":           pos[1],
+		"yPos":           int32(ch.Range()[0] >> 4),
+		"Status":         "minecraft:full",
+		"LastUpdate":     int64(0),
+		"InhabitedTime":  int64(0),
+		"isLightOn":      false,
+		"sections":       sections,
+		"block_entities": javaBlockEntities,
+	}
+}
+
+func (c *converter) convertEntities(pos world.ChunkPos, entities []chunk.Entity) (map[string]any, bool) {
+	var javaEntities []any
+	for _, ent := range entities {
+		if javaEntity, ok := convertEntity(ent.Data); ok {
+			javaEntities = append(javaEntities, javaEntity)
+		}
+	}
+	if len(javaEntities) == 0 {
+		return nil, false
+	}
+	return map[string]any{
+		"DataVersion": int32(DataVersion),
+		"Position":    [2]int32{pos[0], pos[1]},
+		"Entities":    javaEntities,
+	}, true
+}
+
+// dimensionFolder is where java keeps a dimension inside the world folder
+func dimensionFolder(dim world.Dimension) string {
+	switch dim {
+	case world.Nether:
+		return "DIM-1"
+	case world.End:
+		return "DIM1"
+	}
+	return ""
+}
+
+type dimensionWriters struct {
+	regions  *regionWriter
+	entities *regionWriter
+}
+
+func voidDimension(dimensionType string) map[string]any {
+	return map[string]any{
+		"type": dimensionType,
+		"generator": map[string]any{
+			"type": "minecraft:flat",
+			"settings": map[string]any{
+				"layers":   []any{},
+				"biome":    "minecraft:the_void",
+				"features": false,
+				"lakes":    false,
+			},
+		},
+	}
+}
+
+// writeLevelDat writes a java level.dat with void generation for the chunks that werent downloaded
+func writeLevelDat(folder string, settings *world.Settings) error {
+	f, err := os.Create(filepath.Join(folder, "level.dat"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+
+	data := map[string]any{
+		"DataVersion":   int32(DataVersion),
+		"version":       int32(19133),
+		"LevelName":     settings.Name,
+		"SpawnX":        int32(settings.Spawn[0]),
+		"SpawnY":        int32(settings.Spawn[1]),
+		"SpawnZ":        int32(settings.Spawn[2]),
+		"GameType":      int32(1),
+		"allowCommands": true,
+		"initialized":   true,
+		"hardcore":      false,
+		"Difficulty":    uint8(1),
+		"Time":          settings.CurrentTick,
+		"DayTime":       settings.Time,
+		"LastPlayed":    time.Now().UnixMilli(),
+		"Version": map[string]any{
+			"Id":       int32(DataVersion),
+			"Name":     "1.20.4",
+			"Series":   "main",
+			"Snapshot": false,
+		},
+		"WorldGenSettings": map[string]any{
+			"seed":              int64(0),
+			"generate_features": false,
+			"bonus_chest":       false,
+			"dimensions": map[string]any{
+				"minecraft:overworld":  voidDimension("minecraft:overworld"),
+				"minecraft:the_nether": voidDimension("minecraft:the_nether"),
+				"minecraft:the_end":    voidDimension("minecraft:the_end"),
+			},
+		},
+	}
+	if err := nbt.NewEncoderWithEncoding(zw, nbt.BigEndian).Encode(map[string]any{"Data": data}); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ConvertWorld writes the bedrock world in inputFolder as a java world to outputFolder
+func ConvertWorld(inputFolder, outputFolder string) error {
+	log := logrus.WithField("part", "Anvil")
+	blockReg := &merge.BlockRegistry{
+		BlockRegistry: world.DefaultBlockRegistry,
+		Rids:          make(map[uint32]merge.Block),
+	}
+	db, err := mcdb.Config{
+		Log:    slog.Default(),
+		Blocks: blockReg,
+		LDBOptions: &opt.Options{
+			ReadOnly: true,
+		},
+	}.Open(inputFolder)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = os.MkdirAll(outputFolder, 0o777); err != nil {
+		return err
+	}
+
+	c := &converter{
+		blocks:  blockReg,
+		states:  make(map[uint32]javaState),
+		dropped: make(map[string]int),
+	}
+
+	writers := make(map[world.Dimension]*dimensionWriters)
+	defer func() {
+		for _, w := range writers {
+			w.regions.Close()
+			w.entities.Close()
+		}
+	}()
+
+	var count int
+	it := db.NewColumnIterator(nil)
+	defer it.Release()
+	for it.Next() {
+		pos := it.Position()
+		dim := it.Dimension()
+		col := it.Column()
+
+		w, ok := writers[dim]
+		if !ok {
+			folder := filepath.Join(outputFolder, dimensionFolder(dim))
+			w = &dimensionWriters{
+				regions:  newRegionWriter(filepath.Join(folder, "region")),
+				entities: newRegionWriter(filepath.Join(folder, "entities")),
+			}
+			writers[dim] = w
+		}
+
+		if err := w.regions.WriteChunk(pos[0], pos[1], c.convertChunk(pos, col)); err != nil {
+			return fmt.Errorf("chunk %v: %w", pos, err)
+		}
+		if entities, ok := c.convertEntities(pos, col.Entities); ok {
+			if err := w.entities.WriteChunk(pos[0], pos[1], entities); err != nil {
+				return fmt.Errorf("entities %v: %w", pos, err)
+			}
+		}
+		count++
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	for name, n := range c.dropped {
+		log.Debugf("%s has no java version, replaced %d with air", name, n)
+	}
+	if len(c.dropped) > 0 {
+		log.Warnf("Replaced %d kinds of blocks that java doesnt have with air", len(c.dropped))
+	}
+
+	for dim, w := range writers {
+		delete(writers, dim)
+		if err := w.regions.Close(); err != nil {
+			return err
+		}
+		if err := w.entities.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err := writeLevelDat(outputFolder, db.Settings()); err != nil {
+		return err
+	}
+	log.Infof("Wrote %d chunks to %s", count, outputFolder)
+	return nil
+}
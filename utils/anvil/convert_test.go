@@ -0,0 +1,55 @@
+package anvil
+
+import (
+	"math/bits"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func unpackPalette(packed any, count, paletteSize int) []int {
+	arr := reflect.ValueOf(packed)
+	bitsPerEntry := max(4, bits.Len(uint(paletteSize-1)))
+	perLong := 64 / bitsPerEntry
+	indices := make([]int, count)
+	for i := range indices {
+		v := uint64(arr.Index(i / perLong).Int())
+		indices[i] = int(v >> ((i % perLong) * bitsPerEntry) & (1<<bitsPerEntry - 1))
+	}
+	return indices
+}
+
+func TestPackPalette(t *testing.T) {
+	type test struct {
+		paletteSize int
+		longs       int
+	}
+	var tests = []test{
+		// 4 bits, 16 per long
+		{paletteSize: 2, longs: 256},
+		{paletteSize: 16, longs: 256},
+		// 5 bits, 12 per long with 4 bits left over
+		{paletteSize: 17, longs: 342},
+		// 7 bits, 9 per long
+		{paletteSize: 100, longs: 456},
+		// 12 bits, 5 per long
+		{paletteSize: 4096, longs: 820},
+	}
+
+	for _, tt := range tests {
+		indices := make([]int, 4096)
+		for i := range indices {
+			indices[i] = (i * 7919) % tt.paletteSize
+		}
+		packed := packPalette(indices, tt.paletteSize)
+		if kind := reflect.TypeOf(packed).Kind(); kind != reflect.Array {
+			t.Fatalf("palette %d packed into %v, expected an array", tt.paletteSize, kind)
+		}
+		if l := reflect.ValueOf(packed).Len(); l != tt.longs {
+			t.Fatalf("palette %d expected %d longs, got %d", tt.paletteSize, tt.longs, l)
+		}
+		if got := unpackPalette(packed, len(indices), tt.paletteSize); !slices.Equal(got, indices) {
+			t.Fatalf("palette %d did not round trip", tt.paletteSize)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package anvil
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math/rand/v2"
+	"strings"
+)
+
+// entities that have a different id on java
+var renamedEntities = map[string]string{
+	"minecraft:zombie_pigman":      "minecraft:zombified_piglin",
+	"minecraft:evocation_illager":  "minecraft:evoker",
+	"minecraft:villager_v2":        "minecraft:villager",
+	"minecraft:zombie_villager_v2": "minecraft:zombie_villager",
+	"minecraft:ender_crystal":      "minecraft:end_crystal",
+	"minecraft:xp_orb":             "minecraft:experience_orb",
+	"minecraft:lightning_bolt":     "",
+	"minecraft:fishing_hook":       "",
+	"minecraft:player":             "",
+	"minecraft:npc":                "",
+	"minecraft:agent":              "",
+	"minecraft:tripod_camera":      "",
+}
+
+// block entities that have a different id on java, ones not in here are dropped
+var blockEntityIDs = map[string]string{
+	"Chest":             "minecraft:chest",
+	"EnderChest":        "minecraft:ender_chest",
+	"Barrel":            "minecraft:barrel",
+	"ShulkerBox":        "minecraft:shulker_box",
+	"Hopper":            "minecraft:hopper",
+	"Dispenser":         "minecraft:dispenser",
+	"Dropper":           "minecraft:dropper",
+	"Furnace":           "minecraft:furnace",
+	"BlastFurnace":      "minecraft:blast_furnace",
+	"Smoker":            "minecraft:smoker",
+	"BrewingStand":      "minecraft:brewing_stand",
+	"Sign":              "minecraft:sign",
+	"HangingSign":       "minecraft:hanging_sign",
+	"Banner":            "minecraft:banner",
+	"Bed":               "minecraft:bed",
+	"Skull":             "minecraft:skull",
+	"Beacon":            "minecraft:beacon",
+	"Bell":              "minecraft:bell",
+	"Lectern":           "minecraft:lectern",
+	"Jukebox":           "minecraft:jukebox",
+	"Campfire":          "minecraft:campfire",
+	"Beehive":           "minecraft:beehive",
+	"MobSpawner":        "minecraft:mob_spawner",
+	"EnchantTable":      "minecraft:enchanting_table",
+	"Comparator":        "minecraft:comparator",
+	"DaylightDetector":  "minecraft:daylight_detector",
+	"Conduit":           "minecraft:conduit",
+	"ChiseledBookshelf": "minecraft:chiseled_bookshelf",
+	"DecoratedPot":      "minecraft:decorated_pot",
+	"EndGateway":        "minecraft:end_gateway",
+	"EndPortal":         "minecraft:end_portal",
+}
+
+func textComponent(text string) string {
+	data, _ := json.Marshal(map[string]string{"text": text})
+	return string(data)
+}
+
+func floats(v any) []float64 {
+	var out []float64
+	switch v := v.(type) {
+	case []any:
+		for _, f := range v {
+			switch f := f.(type) {
+			case float32:
+				out = append(out, float64(f))
+			case float64:
+				out = append(out, f)
+			}
+		}
+	case []float32:
+		for _, f := range v {
+			out = append(out, float64(f))
+		}
+	}
+	return out
+}
+
+func compounds(v any) []map[string]any {
+	var out []map[string]any
+	switch v := v.(type) {
+	case []any:
+		for _, c := range v {
+			if m, ok := c.(map[string]any); ok {
+				out = append(out, m)
+			}
+		}
+	case []map[string]any:
+		out = v
+	}
+	return out
+}
+
+// convertItem makes a java 1.20.4 item stack, newer versions upgrade it on load
+func convertItem(item map[string]any) (map[string]any, bool) {
+	name, _ := item["Name"].(string)
+	if name == "" || name == "minecraft:air" {
+		return nil, false
+	}
+	count, _ := propertyInt(item["Count"])
+	out := map[string]any{
+		"id":    name,
+		"Count": uint8(count),
+	}
+	if slot, ok := propertyInt(item["Slot"]); ok {
+		out["Slot"] = uint8(slot)
+	}
+	if damage, _ := propertyInt(item["Damage"]); damage > 0 {
+		out["tag"] = map[string]any{"Damage": int32(damage)}
+	}
+	return out, true
+}
+
+func convertItems(v any) []any {
+	items := []any{}
+	for _, item := range compounds(v) {
+		if javaItem, ok := convertItem(item); ok {
+			items = append(items, javaItem)
+		}
+	}
+	return items
+}
+
+func convertSignText(side any) map[string]any {
+	messages := make([]any, 4)
+	for i := range messages {
+		messages[i] = textComponent("")
+	}
+	var glowing bool
+	if side, ok := side.(map[string]any); ok {
+		text, _ := side["Text"].(string)
+		for i, line := range strings.SplitN(text, "\n", 4) {
+			messages[i] = textComponent(line)
+		}
+		glowing = propertyBool(side["IgnoreLighting"])
+	}
+	return map[string]any{
+		"messages":         messages,
+		"color":            "black",
+		"has_glowing_text": glowing,
+	}
+}
+
+// convertBlockEntity translates the data of a bedrock block entity, false if java has no such block entity
+func convertBlockEntity(data map[string]any, x, y, z int32) (map[string]any, bool) {
+	bedrockID, _ := data["id"].(string)
+	id, ok := blockEntityIDs[bedrockID]
+	if !ok {
+		return nil, false
+	}
+	out := map[string]any{
+		"id":         id,
+		"x":          x,
+		"y":          y,
+		"z":          z,
+		"keepPacked": false,
+	}
+	if name, ok := data["CustomName"].(string); ok && name != "" {
+		out["CustomName"] = textComponent(name)
+	}
+	if _, ok := data["Items"]; ok {
+		out["Items"] = convertItems(data["Items"])
+	}
+
+	switch bedrockID {
+	case "Sign", "HangingSign":
+		out["front_text"] = convertSignText(data["FrontText"])
+		out["back_text"] = convertSignText(data["BackText"])
+		out["is_waxed"] = propertyBool(data["IsWaxed"])
+	case "Banner":
+		var patterns []any
+		for _, pattern := range compounds(data["Patterns"]) {
+			color, _ := propertyInt(pattern["Color"])
+			patterns = append(patterns, map[string]any{
+				"Pattern": pattern["Pattern"],
+				"Color":   int32(15 - color),
+			})
+		}
+		if len(patterns) > 0 {
+			out["Patterns"] = patterns
+		}
+	case "Lectern":
+		if book, ok := data["book"].(map[string]any); ok {
+			if javaBook, ok := convertItem(book); ok {
+				out["Book"] = javaBook
+				page, _ := propertyInt(data["page"])
+				out["Page"] = int32(page)
+			}
+		}
+	case "Jukebox":
+		if record, ok := data["RecordItem"].(map[string]any); ok {
+			if javaRecord, ok := convertItem(record); ok {
+				out["RecordItem"] = javaRecord
+			}
+		}
+	}
+	return out, true
+}
+
+func randomUUID() [4]int32 {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], rand.Uint64())
+	binary.BigEndian.PutUint64(b[8:], rand.Uint64())
+	return [4]int32{
+		int32(binary.BigEndian.Uint32(b[0:])),
+		int32(binary.BigEndian.Uint32(b[4:])),
+		int32(binary.BigEndian.Uint32(b[8:])),
+		int32(binary.BigEndian.Uint32(b[12:])),
+	}
+}
+
+// convertEntity translates a saved bedrock entity, false if java has no such entity
+func convertEntity(data map[string]any) (map[string]any, bool) {
+	id, _ := data["identifier"].(string)
+	if renamed, ok := renamedEntities[id]; ok {
+		id = renamed
+	}
+	if !strings.HasPrefix(id, "minecraft:") {
+		return nil, false
+	}
+	pos := floats(data["Pos"])
+	if len(pos) != 3 {
+		return nil, false
+	}
+	rotation := floats(data["Rotation"])
+	if len(rotation) != 2 {
+		rotation = []float64{0, 0}
+	}
+	motion := floats(data["Motion"])
+	if len(motion) != 3 {
+		motion = []float64{0, 0, 0}
+	}
+
+	out := map[string]any{
+		"id":                  id,
+		"Pos":                 pos,
+		"Rotation":            []float32{float32(rotation[0]), float32(rotation[1])},
+		"Motion":              motion,
+		"UUID":                randomUUID(),
+		"PersistenceRequired": true,
+		"OnGround":            true,
+	}
+	if name, ok := data["CustomName"].(string); ok && name != "" {
+		out["CustomName"] = textComponent(name)
+		out["CustomNameVisible"] = propertyBool(data["CustomNameVisible"])
+	}
+	if item, ok := data["Item"].(map[string]any); ok {
+		if javaItem, ok := convertItem(item); ok {
+			delete(javaItem, "Slot")
+			out["Item"] = javaItem
+		}
+	}
+	return out, true
+}
+
+// JavaBlockEntity translates a bedrock block entity at x y z, false if java has no such block entity
+func JavaBlockEntity(data map[string]any, x, y, z int32) (map[string]any, bool) {
+	return convertBlockEntity(data, x, y, z)
+}
+
+// JavaEntity translates a saved bedrock entity, false if java has no such entity
+func JavaEntity(data map[string]any) (map[string]any, bool) {
+	return convertEntity(data)
+}
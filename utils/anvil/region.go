@@ -0,0 +1,110 @@
+// Package anvil writes worlds in the java edition anvil format.
+package anvil
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+)
+
+const (
+	sectorSize = 4096
+	// chunks are zlib compressed
+	compressionZlib = 2
+)
+
+type regionPos [2]int32
+
+// region is one open r.x.z.mca file, chunks are appended and the header is written on close
+type region struct {
+	f          *os.File
+	locations  [1024]uint32
+	timestamps [1024]uint32
+	nextSector uint32
+}
+
+func (r *region) writeChunk(index int, data []byte) error {
+	sectors := (len(data) + 5 + sectorSize - 1) / sectorSize
+	if sectors > 255 {
+		return fmt.Errorf("chunk is too big (%d bytes)", len(data))
+	}
+	buf := make([]byte, sectors*sectorSize)
+	binary.BigEndian.PutUint32(buf, uint32(len(data)+1))
+	buf[4] = compressionZlib
+	copy(buf[5:], data)
+	if _, err := r.f.WriteAt(buf, int64(r.nextSector)*sectorSize); err != nil {
+		return err
+	}
+	r.locations[index] = r.nextSector<<8 | uint32(sectors)
+	r.timestamps[index] = uint32(time.Now().Unix())
+	r.nextSector += uint32(sectors)
+	return nil
+}
+
+func (r *region) close() error {
+	header := make([]byte, 2*sectorSize)
+	for i := range 1024 {
+		binary.BigEndian.PutUint32(header[i*4:], r.locations[i])
+		binary.BigEndian.PutUint32(header[sectorSize+i*4:], r.timestamps[i])
+	}
+	if _, err := r.f.WriteAt(header, 0); err != nil {
+		r.f.Close()
+		return err
+	}
+	return r.f.Close()
+}
+
+// regionWriter writes chunk nbt to the region files in a folder
+type regionWriter struct {
+	folder  string
+	regions map[regionPos]*region
+}
+
+func newRegionWriter(folder string) *regionWriter {
+	return &regionWriter{
+		folder:  folder,
+		regions: make(map[regionPos]*region),
+	}
+}
+
+func (w *regionWriter) WriteChunk(x, z int32, tag map[string]any) error {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if err := nbt.NewEncoderWithEncoding(zw, nbt.BigEndian).Encode(tag); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	pos := regionPos{x >> 5, z >> 5}
+	r, ok := w.regions[pos]
+	if !ok {
+		if err := os.MkdirAll(w.folder, 0o777); err != nil {
+			return err
+		}
+		f, err := os.Create(filepath.Join(w.folder, fmt.Sprintf("r.%d.%d.mca", pos[0], pos[1])))
+		if err != nil {
+			return err
+		}
+		r = &region{f: f, nextSector: 2}
+		w.regions[pos] = r
+	}
+	return r.writeChunk(int(x&31)+int(z&31)*32, buf.Bytes())
+}
+
+func (w *regionWriter) Close() error {
+	var err error
+	for _, r := range w.regions {
+		if err2 := r.close(); err2 != nil && err == nil {
+			err = err2
+		}
+	}
+	return err
+}
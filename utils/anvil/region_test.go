@@ -0,0 +1,90 @@
+package anvil
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+)
+
+func TestRegionHeader(t *testing.T) {
+	folder := t.TempDir()
+	w := newRegionWriter(folder)
+	chunks := map[[2]int32]int32{{0, 0}: 1, {31, 0}: 2, {-1, -1}: 3, {5, 7}: 4}
+	for pos, v := range chunks {
+		if err := w.WriteChunk(pos[0], pos[1], map[string]any{"v": v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{}
+	for _, name := range []string{"r.0.0.mca", "r.-1.-1.mca"} {
+		data, err := os.ReadFile(filepath.Join(folder, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data)%sectorSize != 0 {
+			t.Fatalf("%s is %d bytes, not a whole number of sectors", name, len(data))
+		}
+		files[name] = data
+	}
+
+	used := map[string]map[uint32]bool{}
+	for pos, v := range chunks {
+		name := "r.0.0.mca"
+		if pos[0] < 0 {
+			name = "r.-1.-1.mca"
+		}
+		data := files[name]
+		index := int(pos[0]&31) + int(pos[1]&31)*32
+		location := binary.BigEndian.Uint32(data[index*4:])
+		offset, sectors := location>>8, location&0xff
+		if offset < 2 || sectors == 0 {
+			t.Fatalf("chunk %v has location %d:%d", pos, offset, sectors)
+		}
+		if used[name] == nil {
+			used[name] = map[uint32]bool{}
+		}
+		if used[name][offset] {
+			t.Fatalf("chunk %v shares sector %d", pos, offset)
+		}
+		used[name][offset] = true
+		if binary.BigEndian.Uint32(data[sectorSize+index*4:]) == 0 {
+			t.Fatalf("chunk %v has no timestamp", pos)
+		}
+
+		chunk := data[offset*sectorSize:]
+		length := binary.BigEndian.Uint32(chunk)
+		if chunk[4] != compressionZlib {
+			t.Fatalf("chunk %v compression is %d", pos, chunk[4])
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(chunk[5 : 4+length]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tag map[string]any
+		if err := nbt.UnmarshalEncoding(raw, &tag, nbt.BigEndian); err != nil {
+			t.Fatal(err)
+		}
+		if tag["v"] != v {
+			t.Fatalf("chunk %v expected %d, got %v", pos, v, tag["v"])
+		}
+	}
+
+	// unused entries stay empty
+	if location := binary.BigEndian.Uint32(files["r.0.0.mca"][1*4:]); location != 0 {
+		t.Fatalf("unused entry has location %d", location)
+	}
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"reflect"
@@ -227,3 +228,72 @@ func ParseArgs(ctx context.Context, cmd Command, argValues []string) (any, *flag
 
 	return settings, flags, nil
 }
+
+// jsonArgValue turns a json value into the string form that Arg.Set takes
+func jsonArgValue(raw json.RawMessage) (string, error) {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		var values []string
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return "", fmt.Errorf("expected a list of strings")
+			}
+			values = append(values, s)
+		}
+		return strings.Join(values, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %s", raw)
+}
+
+// ParseJSON creates the settings of cmd from a json object with the flag names as keys,
+// settings that are left out keep their default
+func ParseJSON(cmd Command, data []byte) (any, error) {
+	settings := cmd.Settings()
+
+	args, err := ParseArgsType(reflect.ValueOf(settings), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]json.RawMessage
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, arg := range args {
+		raw, ok := values[arg.Flag]
+		delete(values, arg.Flag)
+		v := arg.Default
+		if ok {
+			if v, err = jsonArgValue(raw); err != nil {
+				return nil, fmt.Errorf("%s: %w", arg.Flag, err)
+			}
+		}
+		if arg.Type == "connectInfo" {
+			arg.SetConnectInfo(&connectinfo.ConnectInfo{Value: v})
+			continue
+		}
+		if err := arg.Set(v); err != nil && ok {
+			return nil, fmt.Errorf("%s: %w", arg.Flag, err)
+		}
+	}
+	for name := range values {
+		return nil, fmt.Errorf("unknown setting %s", name)
+	}
+	return settings, nil
+}
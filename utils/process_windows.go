@@ -0,0 +1,24 @@
+//go:build windows
+
+package utils
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// exit code of a process that didnt exit yet
+const stillActive = 259
+
+// ProcessAlive returns if a process with the pid is running
+func ProcessAlive(pid int) bool {
+	hand, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(hand)
+	var code uint32
+	if err := windows.GetExitCodeProcess(hand, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
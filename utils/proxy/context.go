@@ -33,13 +33,17 @@ type Context struct {
 
 	addedPacks []resource.Pack
 	handlers   []func() *Handler
+
+	// runs a session, tests replace it to not need a server
+	runSession func(s *Session) error
 }
 
 // New creates a new proxy context
 func New(ctx context.Context, settings ProxySettings) (*Context, error) {
 	p := &Context{
-		ctx:      ctx,
-		settings: settings,
+		ctx:        ctx,
+		settings:   settings,
+		runSession: (*Session).Run,
 	}
 	return p, nil
 }
@@ -54,29 +58,49 @@ func (p *Context) Context() context.Context {
 }
 
 func (p *Context) connect(connectInfo *connectinfo.ConnectInfo, withClient bool) (err error) {
-	session := NewSession(p.ctx, p.settings, p.addedPacks, connectInfo, withClient)
-	for _, handlerFunc := range p.handlers {
-		session.handlers = append(session.handlers, handlerFunc())
+	var reconnect *reconnector
+	if p.settings.Reconnect != 0 && !connectInfo.IsReplay() {
+		reconnect = &reconnector{ctx: p.ctx, attempts: p.settings.Reconnect}
 	}
 
-	serverName, err := connectInfo.Name(p.ctx)
-	if err != nil {
-		return err
-	}
+	resumed := false
+	for {
+		session := NewSession(p.ctx, p.settings, p.addedPacks, connectInfo, withClient)
+		session.reconnector = reconnect
+		session.resumed = resumed
+		for _, handlerFunc := range p.handlers {
+			session.handlers = append(session.handlers, handlerFunc())
+		}
+		session.addRegisteredHandlers(p.ctx, p.settings.Handlers)
+		session.addHandlerCommand()
 
-	session.handlers.SessionStart(session, serverName)
-	err = session.Run()
-	session.handlers.OnSessionEnd(session, &p.wg)
+		serverName, err := connectInfo.Name(p.ctx)
+		if err != nil {
+			return err
+		}
 
-	if err, ok := err.(*errTransfer); ok {
-		if connectInfo.IsReplay() {
-			return nil
+		session.handlers.SessionStart(session, serverName)
+		addSession(session)
+		err = p.runSession(session)
+		removeSession(session)
+		session.handlers.OnSessionEnd(session, &p.wg)
+
+		if session.reconnecting {
+			resumed = true
+			logrus.Info("reconnecting")
+			continue
+		}
+
+		if err, ok := err.(*errTransfer); ok {
+			if connectInfo.IsReplay() {
+				return nil
+			}
+			address := fmt.Sprintf("%s:%d", err.transfer.Address, err.transfer.Port)
+			logrus.Infof("transferring to %s", address)
+			return p.connect(&connectinfo.ConnectInfo{Value: address, Account: connectInfo.Account}, withClient)
 		}
-		address := fmt.Sprintf("%s:%d", err.transfer.Address, err.transfer.Port)
-		logrus.Infof("transferring to %s", address)
-		return p.connect(&connectinfo.ConnectInfo{Value: address, Account: connectInfo.Account}, withClient)
+		return err
 	}
-	return err
 }
 
 func (p *Context) Run(ctx context.Context, withClient bool) (err error) {
@@ -100,9 +124,27 @@ func (p *Context) Run(ctx context.Context, withClient bool) (err error) {
 		p.settings.ConnectInfo.Account = auth.Auth.Account()
 	}
 
+	if err = checkHandlerNames(p.settings.Handlers); err != nil {
+		return err
+	}
 	if p.settings.Capture {
 		p.AddHandler(NewPacketCapturer)
 	}
+	if p.settings.Rules != "" {
+		rules, err := NewRulesHandler(p.settings.Rules)
+		if err != nil {
+			return err
+		}
+		p.AddHandler(rules)
+	}
+	if p.settings.Script != "" {
+		script, err := NewScriptHandler(p.settings.Script)
+		if err != nil {
+			return err
+		}
+		// first so it sees packets before the other handlers and can drop them
+		p.handlers = append([]func() *Handler{script}, p.handlers...)
+	}
 	p.addedPacks, err = loadForcedPacks()
 	if err != nil {
 		return err
@@ -2,7 +2,10 @@ package proxy
 
 import (
 	"net"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sandertv/gophertunnel/minecraft"
@@ -33,11 +36,39 @@ type Handler struct {
 	OnBlobs      func(s *Session, blobs []protocol.CacheBlob)
 
 	OnPlayerMove func(s *Session)
+
+	// name the handler was registered with, if it came from the registry
+	key      string
+	disabled atomic.Bool
+}
+
+// Enabled returns if the handler is called, handlers can be switched off while the session runs
+func (h *Handler) Enabled() bool {
+	return !h.disabled.Load()
+}
+
+// SetEnabled switches the handler on or off, OnSessionEnd is always called so it can clean up
+func (h *Handler) SetEnabled(enabled bool) {
+	h.disabled.Store(!enabled)
+}
+
+// find returns the handler with the name or registered name, ignoring case and spaces
+func (h Handlers) find(name string) *Handler {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	}
+	name = normalize(name)
+	for _, handler := range h {
+		if normalize(handler.Name) == name || handler.key != "" && handler.key == name {
+			return handler
+		}
+	}
+	return nil
 }
 
 func (h Handlers) SessionStart(s *Session, serverName string) error {
 	for _, handler := range h {
-		if handler.SessionStart == nil {
+		if handler.SessionStart == nil || handler.disabled.Load() {
 			continue
 		}
 		err := handler.SessionStart(s, serverName)
@@ -50,7 +81,7 @@ func (h Handlers) SessionStart(s *Session, serverName string) error {
 
 func (h Handlers) GameDataModifier(s *Session, gameData *minecraft.GameData) {
 	for _, handler := range h {
-		if handler.GameDataModifier == nil {
+		if handler.GameDataModifier == nil || handler.disabled.Load() {
 			continue
 		}
 		handler.GameDataModifier(s, gameData)
@@ -59,7 +90,7 @@ func (h Handlers) GameDataModifier(s *Session, gameData *minecraft.GameData) {
 
 func (h Handlers) PlayerDataModifier(s *Session, identity *login.IdentityData, data *login.ClientData) {
 	for _, handler := range h {
-		if handler.PlayerDataModifier == nil {
+		if handler.PlayerDataModifier == nil || handler.disabled.Load() {
 			continue
 		}
 		handler.PlayerDataModifier(s, identity, data)
@@ -68,7 +99,7 @@ func (h Handlers) PlayerDataModifier(s *Session, identity *login.IdentityData, d
 
 func (h Handlers) FilterResourcePack(s *Session, id string) bool {
 	for _, handler := range h {
-		if handler.FilterResourcePack == nil {
+		if handler.FilterResourcePack == nil || handler.disabled.Load() {
 			continue
 		}
 		if handler.FilterResourcePack(s, id) {
@@ -80,7 +111,7 @@ func (h Handlers) FilterResourcePack(s *Session, id string) bool {
 
 func (h Handlers) OnFinishedPack(s *Session, pack resource.Pack) error {
 	for _, handler := range h {
-		if handler.OnFinishedPack == nil {
+		if handler.OnFinishedPack == nil || handler.disabled.Load() {
 			continue
 		}
 		err := handler.OnFinishedPack(s, pack)
@@ -93,16 +124,26 @@ func (h Handlers) OnFinishedPack(s *Session, pack resource.Pack) error {
 
 func (h Handlers) PacketRaw(s *Session, header packet.Header, payload []byte, src, dst net.Addr, timeReceived time.Time) {
 	for _, handler := range h {
-		if handler.PacketRaw == nil {
+		if handler.PacketRaw == nil || handler.disabled.Load() {
 			continue
 		}
 		handler.PacketRaw(s, header, payload, src, dst, timeReceived)
 	}
 }
+
+// after returns the handlers that come after handler, none if it isnt one of them
+func (h Handlers) after(handler *Handler) Handlers {
+	i := slices.Index(h, handler)
+	if i < 0 {
+		return nil
+	}
+	return h[i+1:]
+}
+
 func (h Handlers) PacketCallback(s *Session, pk packet.Packet, toServer bool, timeReceived time.Time, preLogin bool) (packet.Packet, error) {
 	var err error
 	for _, handler := range h {
-		if handler.PacketCallback == nil {
+		if handler.PacketCallback == nil || handler.disabled.Load() {
 			continue
 		}
 		pk, err = handler.PacketCallback(s, pk, toServer, timeReceived, preLogin)
@@ -118,7 +159,7 @@ func (h Handlers) PacketCallback(s *Session, pk packet.Packet, toServer bool, ti
 
 func (h Handlers) OnServerConnect(s *Session) (cancel bool, err error) {
 	for _, handler := range h {
-		if handler.OnServerConnect == nil {
+		if handler.OnServerConnect == nil || handler.disabled.Load() {
 			continue
 		}
 		cancel, err = handler.OnServerConnect(s)
@@ -134,7 +175,7 @@ func (h Handlers) OnServerConnect(s *Session) (cancel bool, err error) {
 
 func (h Handlers) OnConnect(s *Session) (cancel bool) {
 	for _, handler := range h {
-		if handler.OnConnect == nil {
+		if handler.OnConnect == nil || handler.disabled.Load() {
 			continue
 		}
 		if handler.OnConnect(s) {
@@ -155,7 +196,7 @@ func (h Handlers) OnSessionEnd(s *Session, wg *sync.WaitGroup) {
 
 func (h Handlers) OnBlobs(s *Session, blobs []protocol.CacheBlob) {
 	for _, handler := range h {
-		if handler.OnBlobs == nil {
+		if handler.OnBlobs == nil || handler.disabled.Load() {
 			continue
 		}
 		handler.OnBlobs(s, blobs)
@@ -164,7 +205,7 @@ func (h Handlers) OnBlobs(s *Session, blobs []protocol.CacheBlob) {
 
 func (h Handlers) OnPlayerMove(s *Session) {
 	for _, handler := range h {
-		if handler.OnPlayerMove == nil {
+		if handler.OnPlayerMove == nil || handler.disabled.Load() {
 			continue
 		}
 		handler.OnPlayerMove(s)
@@ -0,0 +1,281 @@
+package pcap2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sirupsen/logrus"
+)
+
+// IndexEntry describes where a single packet is stored in a capture
+type IndexEntry struct {
+	Offset   int64
+	Time     time.Time
+	PacketID uint32
+	ToServer bool
+}
+
+const (
+	indexMagic     = "BTCI"
+	indexVersion   = 1
+	indexEntrySize = 8 + 8 + 4 + 1
+	// magic, version, capture size, capture mod time and entry count
+	indexHeaderSize = 4 + 4 + 8 + 8 + 8
+)
+
+// IndexPath returns the path of the sidecar index for a capture file
+func IndexPath(capturePath string) string {
+	return capturePath + ".idx"
+}
+
+// readPacketHead reads the framing in front of a packet payload
+func readPacketHead(r io.Reader) (packetLength uint32, toServer bool, receivedTime time.Time, err error) {
+	var head [4 + 4 + 1 + 8]byte
+	_, err = io.ReadFull(r, head[:])
+	if err != nil {
+		return 0, false, time.Time{}, err
+	}
+	magic := binary.LittleEndian.Uint32(head[:])
+	if magic != 0xAAAAAAAA {
+		return 0, false, time.Time{}, fmt.Errorf("wrong Magic")
+	}
+	packetLength = binary.LittleEndian.Uint32(head[4:])
+	toServer = head[8] == 1
+	receivedTime = time.UnixMilli(int64(binary.LittleEndian.Uint64(head[9:])))
+	return packetLength, toServer, receivedTime, nil
+}
+
+// scanIndex reads every packet in r and returns where each one starts,
+// start is the file offset r begins at
+func scanIndex(r io.Reader, start int64) ([]IndexEntry, error) {
+	br := bufio.NewReaderSize(r, 1<<20)
+	var index []IndexEntry
+	var payload []byte
+	off := start
+	for {
+		packetLength, toServer, receivedTime, err := readPacketHead(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return index, nil
+			}
+			return nil, err
+		}
+
+		if cap(payload) < int(packetLength)+4 {
+			payload = make([]byte, packetLength+4)
+		}
+		payload = payload[:packetLength+4]
+		if _, err := io.ReadFull(br, payload); err != nil {
+			// capture was cut off while writing the last packet
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return index, nil
+			}
+			return nil, err
+		}
+		if binary.LittleEndian.Uint32(payload[packetLength:]) != 0xBBBBBBBB {
+			return nil, errors.New("wrong Magic2")
+		}
+
+		decoded, err := s2.Decode(nil, payload[:packetLength])
+		if err != nil {
+			return nil, err
+		}
+		var header packet.Header
+		if err := header.Read(bytes.NewBuffer(decoded)); err != nil {
+			return nil, err
+		}
+
+		index = append(index, IndexEntry{
+			Offset:   off,
+			Time:     receivedTime,
+			PacketID: header.PacketID,
+			ToServer: toServer,
+		})
+		off += int64(17 + len(payload))
+	}
+}
+
+// BuildIndex reads the whole capture and indexes every packet in it
+func (r *Pcap2Reader) BuildIndex() error {
+	if r.Version < 5 {
+		return errors.New("capture version < 5 cannot be indexed")
+	}
+	stat, err := r.f.Stat()
+	if err != nil {
+		return err
+	}
+	index, err := scanIndex(io.NewSectionReader(r.f, r.packetsStart, stat.Size()-r.packetsStart), r.packetsStart)
+	if err != nil {
+		return err
+	}
+	r.index = index
+	r.indexComplete = true
+	return nil
+}
+
+// LoadIndex reads a sidecar index, it fails if the index doesnt belong to this capture anymore
+func (r *Pcap2Reader) LoadIndex(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+
+	var head [indexHeaderSize]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		return err
+	}
+	if string(head[0:4]) != indexMagic {
+		return errors.New("not a capture index")
+	}
+	if binary.LittleEndian.Uint32(head[4:]) != indexVersion {
+		return errors.New("unsupported capture index version")
+	}
+	stat, err := r.f.Stat()
+	if err != nil {
+		return err
+	}
+	captureSize := int64(binary.LittleEndian.Uint64(head[8:]))
+	captureModTime := int64(binary.LittleEndian.Uint64(head[16:]))
+	if captureSize != stat.Size() || captureModTime != stat.ModTime().UnixNano() {
+		return errors.New("capture index is outdated")
+	}
+
+	indexStat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	count := binary.LittleEndian.Uint64(head[24:])
+	if count > uint64(indexStat.Size()-indexHeaderSize)/indexEntrySize {
+		return errors.New("capture index is truncated")
+	}
+	index := make([]IndexEntry, 0, count)
+	var entry [indexEntrySize]byte
+	for range count {
+		if _, err := io.ReadFull(br, entry[:]); err != nil {
+			return err
+		}
+		index = append(index, IndexEntry{
+			Offset:   int64(binary.LittleEndian.Uint64(entry[0:])),
+			Time:     time.UnixMilli(int64(binary.LittleEndian.Uint64(entry[8:]))),
+			PacketID: binary.LittleEndian.Uint32(entry[16:]),
+			ToServer: entry[20] == 1,
+		})
+	}
+	r.index = index
+	r.indexComplete = true
+	return nil
+}
+
+// WriteIndex writes the index to a sidecar file, BuildIndex or LoadIndex has to be called first
+func (r *Pcap2Reader) WriteIndex(filename string) error {
+	if !r.indexComplete {
+		return errors.New("index is not complete")
+	}
+	stat, err := r.f.Stat()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+
+	buf := []byte(indexMagic)
+	buf = binary.LittleEndian.AppendUint32(buf, indexVersion)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(stat.Size()))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(stat.ModTime().UnixNano()))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(r.index)))
+	bw.Write(buf)
+	for _, entry := range r.index {
+		buf = buf[:0]
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(entry.Offset))
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(entry.Time.UnixMilli()))
+		buf = binary.LittleEndian.AppendUint32(buf, entry.PacketID)
+		if entry.ToServer {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		bw.Write(buf)
+	}
+	return bw.Flush()
+}
+
+// OpenIndex loads the sidecar index of the capture,
+// if it is missing or outdated it gets rebuilt and written back
+func (r *Pcap2Reader) OpenIndex(capturePath string) error {
+	if r.indexComplete {
+		return nil
+	}
+	indexPath := IndexPath(capturePath)
+	err := r.LoadIndex(indexPath)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		logrus.Debugf("Rebuilding capture index: %s", err)
+	}
+
+	logrus.Info("Indexing capture")
+	if err := r.BuildIndex(); err != nil {
+		return err
+	}
+	if err := r.WriteIndex(indexPath); err != nil {
+		logrus.Warnf("Failed to write capture index: %s", err)
+	}
+	return nil
+}
+
+// Index returns all packets that have been indexed so far
+func (r *Pcap2Reader) Index() []IndexEntry {
+	return r.index
+}
+
+// StartTime returns the time the first packet was received at
+func (r *Pcap2Reader) StartTime() (time.Time, error) {
+	if len(r.index) > 0 {
+		return r.index[0].Time, nil
+	}
+	f := io.NewSectionReader(r.f, r.packetsStart, 17)
+	_, _, receivedTime, err := readPacketHead(f)
+	return receivedTime, err
+}
+
+// PacketAt returns the number of the first packet received at or after t
+func (r *Pcap2Reader) PacketAt(t time.Time) (int, error) {
+	if !r.indexComplete {
+		if err := r.BuildIndex(); err != nil {
+			return 0, err
+		}
+	}
+	packetNumber := sort.Search(len(r.index), func(i int) bool {
+		return !r.index[i].Time.Before(t)
+	})
+	if packetNumber == len(r.index) {
+		return 0, io.EOF
+	}
+	return packetNumber, nil
+}
+
+// SeekTime moves the reader to the first packet received at or after t and returns its number
+func (r *Pcap2Reader) SeekTime(t time.Time) (int, error) {
+	packetNumber, err := r.PacketAt(t)
+	if err != nil {
+		return 0, err
+	}
+	return packetNumber, r.Seek(packetNumber)
+}
@@ -0,0 +1,91 @@
+package pcap2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func appendTestPacket(buf []byte, id uint32, toServer bool, t time.Time) []byte {
+	payload := bytes.NewBuffer(nil)
+	header := packet.Header{PacketID: id}
+	header.Write(payload)
+	payload.Write([]byte{1, 2, 3})
+	return AppendPacket(buf, toServer, payload.Bytes(), t)
+}
+
+func TestScanIndex(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	type test struct {
+		id       uint32
+		toServer bool
+		time     time.Time
+	}
+	var tests = []test{
+		{id: packet.IDStartGame, toServer: false, time: start},
+		{id: packet.IDPlayerAuthInput, toServer: true, time: start.Add(50 * time.Millisecond)},
+		{id: packet.IDLevelChunk, toServer: false, time: start.Add(time.Minute)},
+	}
+
+	var data []byte
+	var offsets []int64
+	for _, tt := range tests {
+		offsets = append(offsets, int64(len(data))+100)
+		data = appendTestPacket(data, tt.id, tt.toServer, tt.time)
+	}
+	// a half written packet at the end should be ignored
+	data = append(data, 0xAA, 0xAA, 0xAA)
+
+	index, err := scanIndex(bytes.NewReader(data), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != len(tests) {
+		t.Fatalf("expected %d entries, got %d", len(tests), len(index))
+	}
+	for i, tt := range tests {
+		entry := index[i]
+		if entry.Offset != offsets[i] || entry.PacketID != tt.id || entry.ToServer != tt.toServer || !entry.Time.Equal(tt.time) {
+			t.Fatalf("entry %d expected: %v %d %v %s\ngot: %+v\n", i, offsets[i], tt.id, tt.toServer, tt.time, entry)
+		}
+	}
+}
+
+func TestLoadIndexTruncated(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "capture.pcap2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Write(appendTestPacket(nil, packet.IDStartGame, false, time.UnixMilli(1700000000000)))
+	stat, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, count := range []uint64{1, 1 << 40, math.MaxUint64} {
+		buf := []byte(indexMagic)
+		buf = binary.LittleEndian.AppendUint32(buf, indexVersion)
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(stat.Size()))
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(stat.ModTime().UnixNano()))
+		buf = binary.LittleEndian.AppendUint64(buf, count)
+		// only part of one entry
+		buf = append(buf, 1, 2, 3)
+		indexPath := filepath.Join(dir, "capture.pcap2.idx")
+		if err := os.WriteFile(indexPath, buf, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		r := &Pcap2Reader{f: f}
+		if err := r.LoadIndex(indexPath); err == nil {
+			t.Fatalf("index with %d entries should not load", count)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package pcap2
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+var packetIDsByName = sync.OnceValue(func() map[string]uint32 {
+	ids := make(map[string]uint32)
+	for _, pool := range []packet.Pool{
+		minecraft.DefaultProtocol.Packets(true),
+		minecraft.DefaultProtocol.Packets(false),
+	} {
+		for id, pkFunc := range pool {
+			ids[strings.ToLower(PacketName(pkFunc()))] = id
+		}
+	}
+	return ids
+})
+
+// PacketName returns the go type name of a packet, e.g. LevelChunk
+func PacketName(pk packet.Packet) string {
+	name := reflect.TypeOf(pk).String()
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// NewPacketByName creates an empty packet from its name or id
+func NewPacketByName(name string) (packet.Packet, bool) {
+	id, ok := PacketIDByName(name)
+	if !ok {
+		return nil, false
+	}
+	for _, pool := range []packet.Pool{
+		minecraft.DefaultProtocol.Packets(false),
+		minecraft.DefaultProtocol.Packets(true),
+	} {
+		if pkFunc, ok := pool[id]; ok {
+			return pkFunc(), true
+		}
+	}
+	return nil, false
+}
+
+// PacketIDByName looks up a packet id by its name (case insensitive) or number
+func PacketIDByName(name string) (uint32, bool) {
+	if id, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(id), true
+	}
+	id, ok := packetIDsByName()[strings.ToLower(name)]
+	return id, ok
+}
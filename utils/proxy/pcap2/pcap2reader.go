@@ -1,10 +1,10 @@
 package pcap2
 
 import (
+	"bytes"
 	"compress/flate"
 	"encoding/binary"
 	"errors"
-	"fmt"
 	"io"
 	"maps"
 	"net"
@@ -21,12 +21,16 @@ import (
 )
 
 type Pcap2Reader struct {
-	f                 *os.File
-	Version           uint32
-	packetsReader     io.Reader
-	ResourcePacks     resourcepacks.PackCache
-	packetOffsetIndex []int64
-	CurrentPacket     int
+	f             *os.File
+	Version       uint32
+	packetsReader io.Reader
+	packetsStart  int64
+	ResourcePacks resourcepacks.PackCache
+	CurrentPacket int
+
+	// index of packets read so far, or all packets once indexComplete is set
+	index         []IndexEntry
+	indexComplete bool
 
 	pool     packet.Pool
 	protocol minecraft.Protocol
@@ -80,22 +84,22 @@ func NewPcap2Reader(f *os.File) (*Pcap2Reader, error) {
 		f:             f,
 		Version:       ver,
 		packetsReader: packetReader,
+		packetsStart:  zipSize + 16,
 		ResourcePacks: cache,
 		pool:          pool,
 		protocol:      minecraft.DefaultProtocol,
 	}, nil
 }
 
-func (r *Pcap2Reader) ReadPacket(skip bool) (pk packet.Packet, toServer bool, receivedTime time.Time, err error) {
+// read reads the next packet and returns its decompressed data, nil if skip is set
+func (r *Pcap2Reader) read(skip bool) (payload []byte, toServer bool, receivedTime time.Time, err error) {
 	// add where this is to index
-	if len(r.packetOffsetIndex) <= r.CurrentPacket && r.Version >= 5 {
-		off, _ := r.f.Seek(0, 1)
-		r.packetOffsetIndex = append(r.packetOffsetIndex, off)
+	var off int64 = -1
+	if len(r.index) <= r.CurrentPacket && r.Version >= 5 {
+		off, _ = r.f.Seek(0, io.SeekCurrent)
 	}
-	r.CurrentPacket++
 
-	var head = make([]byte, 4+4+1+8)
-	_, err = io.ReadFull(r.packetsReader, head)
+	packetLength, toServer, receivedTime, err := readPacketHead(r.packetsReader)
 	if err != nil {
 		if err == io.ErrUnexpectedEOF {
 			err = io.EOF
@@ -106,96 +110,159 @@ func (r *Pcap2Reader) ReadPacket(skip bool) (pk packet.Packet, toServer bool, re
 		}
 		return nil, false, receivedTime, err
 	}
-
-	magic := binary.LittleEndian.Uint32(head)
-	if magic != 0xAAAAAAAA {
-		return nil, toServer, receivedTime, fmt.Errorf("wrong Magic")
+	r.CurrentPacket++
+	if off >= 0 {
+		r.index = append(r.index, IndexEntry{
+			Offset:   off,
+			Time:     receivedTime,
+			ToServer: toServer,
+		})
 	}
-	packetLength := binary.LittleEndian.Uint32(head[4:])
-	toServer = head[8] == 1
-	receivedTime = time.UnixMilli(int64(binary.LittleEndian.Uint64(head[9:])))
 
 	if skip {
 		_, err := io.CopyN(io.Discard, r.packetsReader, int64(packetLength)+4)
 		if err != nil {
 			return nil, toServer, receivedTime, err
 		}
-	} else {
-		payload := make([]byte, packetLength+4)
-		n, err := io.ReadFull(r.packetsReader, payload)
+		return nil, toServer, receivedTime, nil
+	}
+
+	payload = make([]byte, packetLength+4)
+	n, err := io.ReadFull(r.packetsReader, payload)
+	if err != nil {
+		return nil, toServer, receivedTime, err
+	}
+	if n < int(packetLength)+4 {
+		return nil, toServer, receivedTime, errors.New("truncated")
+	}
+
+	magic2 := binary.LittleEndian.Uint32(payload[len(payload)-4:])
+	if magic2 != 0xBBBBBBBB {
+		return nil, toServer, receivedTime, errors.New("wrong Magic2")
+	}
+
+	payload = payload[:len(payload)-4]
+
+	// version 5 compresses payloads seperately
+	if r.Version >= 5 {
+		payload, err = s2.Decode(nil, payload)
 		if err != nil {
 			return nil, toServer, receivedTime, err
 		}
-		if n < int(packetLength)+4 {
-			return nil, toServer, receivedTime, errors.New("truncated")
-		}
+	}
 
-		magic2 := binary.LittleEndian.Uint32(payload[len(payload)-4:])
-		if magic2 != 0xBBBBBBBB {
-			return nil, toServer, receivedTime, errors.New("wrong Magic2")
+	if off >= 0 {
+		var header packet.Header
+		if err := header.Read(bytes.NewBuffer(payload)); err == nil {
+			r.index[len(r.index)-1].PacketID = header.PacketID
 		}
+	}
+	return payload, toServer, receivedTime, nil
+}
 
-		payload = payload[:len(payload)-4]
+// ReadRaw reads the next packet without decoding it, the data starts with the packet header
+func (r *Pcap2Reader) ReadRaw() (payload []byte, toServer bool, receivedTime time.Time, err error) {
+	return r.read(false)
+}
 
-		// version 5 compresses payloads seperately
-		if r.Version >= 5 {
-			payload, err = s2.Decode(nil, payload)
-			if err != nil {
-				return nil, toServer, receivedTime, err
-			}
-		}
+func (r *Pcap2Reader) ReadPacket(skip bool) (pk packet.Packet, toServer bool, receivedTime time.Time, err error) {
+	payload, toServer, receivedTime, err := r.read(skip)
+	if err != nil || skip {
+		return nil, toServer, receivedTime, err
+	}
+	pk, err = r.DecodePacket(payload, toServer, receivedTime)
+	return pk, toServer, receivedTime, err
+}
 
-		var src, dst = replayRemoteAddr, replayLocalAddr
-		if toServer {
-			src, dst = replayLocalAddr, replayRemoteAddr
-		}
-		pkData, err := minecraft.ParseData(payload)
-		if err != nil {
-			return nil, toServer, receivedTime, err
-		}
+// DecodePacket decodes a packet returned by ReadRaw,
+// the item registry has to be decoded before packets that contain items
+func (r *Pcap2Reader) DecodePacket(payload []byte, toServer bool, receivedTime time.Time) (packet.Packet, error) {
+	var src, dst = replayRemoteAddr, replayLocalAddr
+	if toServer {
+		src, dst = replayLocalAddr, replayRemoteAddr
+	}
+	pkData, err := minecraft.ParseData(payload)
+	if err != nil {
+		return nil, err
+	}
+	if r.PacketFunc != nil {
 		r.PacketFunc(*pkData.Header, pkData.Payload.Bytes(), src, dst, receivedTime)
+	}
 
-		pks, err := pkData.Decode(r.pool, r.protocol, nil, false, false, r.shieldID.Load())
-		if err != nil {
-			return nil, toServer, receivedTime, err
-		}
-		pk = pks[0]
+	pks, err := pkData.Decode(r.pool, r.protocol, nil, false, false, r.shieldID.Load())
+	if err != nil {
+		return nil, err
+	}
+	pk := pks[0]
 
-		if pk, ok := pk.(*packet.ItemRegistry); ok {
-			for _, item := range pk.Items {
-				if item.Name == "minecraft:shield" {
-					r.shieldID.Store(int32(item.RuntimeID))
-				}
+	if pk, ok := pk.(*packet.ItemRegistry); ok {
+		for _, item := range pk.Items {
+			if item.Name == "minecraft:shield" {
+				r.shieldID.Store(int32(item.RuntimeID))
 			}
 		}
 	}
 
-	return pk, toServer, receivedTime, nil
+	return pk, nil
 }
 
-func (r *Pcap2Reader) Seek(packet int) error {
+// ZipSection returns the resource pack zip stored in front of the packets
+func (r *Pcap2Reader) ZipSection() *io.SectionReader {
+	return io.NewSectionReader(r.f, 16, r.packetsStart-16)
+}
+
+// ReadFrame returns the still compressed frame of an indexed packet as AppendPacket writes it,
+// it doesnt move the reader
+func (r *Pcap2Reader) ReadFrame(packetNumber int) ([]byte, error) {
+	if packetNumber >= len(r.index) {
+		return nil, errors.New("packet not indexed")
+	}
+	off := r.index[packetNumber].Offset
+	head := io.NewSectionReader(r.f, off, 17)
+	packetLength, _, _, err := readPacketHead(head)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, 17+int(packetLength)+4)
+	if _, err = r.f.ReadAt(frame, off); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(frame[len(frame)-4:]) != 0xBBBBBBBB {
+		return nil, errors.New("wrong Magic2")
+	}
+	return frame, nil
+}
+
+// Seek moves the reader to the start of packetNumber,
+// packets that havent been indexed yet are skipped over from the last known one
+func (r *Pcap2Reader) Seek(packetNumber int) error {
 	if r.Version < 5 {
 		return errors.New("capture version < 5 cannot seek")
 	}
-	diff := packet - r.CurrentPacket
-	if diff == 0 {
+	if packetNumber == r.CurrentPacket {
 		return nil
-	} else if diff > 0 {
-		for i := 0; i < diff; i++ {
-			_, _, _, err := r.ReadPacket(true)
-			if err != nil {
-				return err
-			}
-		}
-	} else if diff < 0 {
-		off := r.packetOffsetIndex[packet]
-		_, err := r.f.Seek(off, 0)
+	}
+
+	if packetNumber < len(r.index) {
+		_, err := r.f.Seek(r.index[packetNumber].Offset, io.SeekStart)
 		if err != nil {
 			return err
 		}
-		r.CurrentPacket = packet
+		r.CurrentPacket = packetNumber
 		return nil
 	}
+
+	if last := len(r.index) - 1; last > r.CurrentPacket {
+		if err := r.Seek(last); err != nil {
+			return err
+		}
+	}
+	for r.CurrentPacket < packetNumber {
+		_, _, _, err := r.ReadPacket(true)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -204,8 +271,8 @@ func (r *Pcap2Reader) ReadBack() (pk packet.Packet, toServer bool, receivedTime
 		return nil, false, time.Time{}, io.EOF
 	}
 	r.CurrentPacket--
-	off := r.packetOffsetIndex[r.CurrentPacket]
-	_, err = r.f.Seek(off, 0)
+	off := r.index[r.CurrentPacket].Offset
+	_, err = r.f.Seek(off, io.SeekStart)
 	if err != nil {
 		return nil, false, time.Time{}, io.EOF
 	}
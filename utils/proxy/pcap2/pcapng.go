@@ -0,0 +1,387 @@
+package pcap2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LinkTypeBedrock is the pcapng link type of exported captures (LINKTYPE_USER0).
+	// every frame is an ipv4 and udp header followed by 0xFE and the decompressed game packet,
+	// packets from the client come from 1.1.1.1:19133, packets from the server from 2.2.2.2:19132
+	LinkTypeBedrock = 147
+
+	// PEN in the custom block that holds the resource pack zip (the IANA documentation PEN)
+	pcapngPEN = 32473
+
+	pcapngBlockSectionHeader  = 0x0A0D0D0A
+	pcapngBlockInterface      = 0x00000001
+	pcapngBlockEnhancedPacket = 0x00000006
+	pcapngBlockCustom         = 0x00000BAD
+	pcapngByteOrderMagic      = 0x1A2B3C4D
+
+	pcapngOptEnd       = 0
+	pcapngOptComment   = 1
+	pcapngOptShbUserAp = 4
+	pcapngOptIfName    = 2
+	pcapngOptIfTsresol = 9
+
+	linkTypeRaw  = 101
+	linkTypeIPv4 = 228
+)
+
+var (
+	pcapngClientIP   = net.IPv4(1, 1, 1, 1).To4()
+	pcapngServerIP   = net.IPv4(2, 2, 2, 2).To4()
+	pcapngClientPort = uint16(19133)
+	pcapngServerPort = uint16(19132)
+)
+
+func appendPadding(buf []byte, n int) []byte {
+	return append(buf, make([]byte, (4-n%4)%4)...)
+}
+
+func appendOption(buf []byte, code uint16, value []byte) []byte {
+	buf = binary.LittleEndian.AppendUint16(buf, code)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(value)))
+	buf = append(buf, value...)
+	return appendPadding(buf, len(value))
+}
+
+func appendOptionEnd(buf []byte) []byte {
+	return binary.LittleEndian.AppendUint32(buf, pcapngOptEnd)
+}
+
+func writePcapngBlock(w io.Writer, blockType uint32, body []byte) error {
+	total := uint32(12 + len(body))
+	buf := make([]byte, 0, total)
+	buf = binary.LittleEndian.AppendUint32(buf, blockType)
+	buf = binary.LittleEndian.AppendUint32(buf, total)
+	buf = append(buf, body...)
+	buf = binary.LittleEndian.AppendUint32(buf, total)
+	_, err := w.Write(buf)
+	return err
+}
+
+func ipv4Checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// appendFrame wraps a packet in an ipv4 and udp header,
+// lengths that dont fit are set to 0 like wireshark does for segmentation offloaded packets
+func appendFrame(buf []byte, toServer bool, payload []byte) []byte {
+	src, dst := pcapngServerIP, pcapngClientIP
+	srcPort, dstPort := pcapngServerPort, pcapngClientPort
+	if toServer {
+		src, dst = dst, src
+		srcPort, dstPort = dstPort, srcPort
+	}
+
+	udpLength := 8 + 1 + len(payload)
+	ipLength := 20 + udpLength
+	if udpLength > math.MaxUint16 {
+		udpLength = 0
+	}
+	if ipLength > math.MaxUint16 {
+		ipLength = 0
+	}
+
+	ipStart := len(buf)
+	buf = append(buf, 0x45, 0)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(ipLength))
+	buf = append(buf, 0, 0, 0x40, 0, 64, 17, 0, 0)
+	buf = append(buf, src...)
+	buf = append(buf, dst...)
+	binary.BigEndian.PutUint16(buf[ipStart+10:], ipv4Checksum(buf[ipStart:]))
+
+	buf = binary.BigEndian.AppendUint16(buf, srcPort)
+	buf = binary.BigEndian.AppendUint16(buf, dstPort)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(udpLength))
+	buf = append(buf, 0, 0)
+
+	buf = append(buf, 0xFE)
+	return append(buf, payload...)
+}
+
+// parseFrame undoes appendFrame
+func parseFrame(frame []byte) (payload []byte, toServer bool, err error) {
+	if len(frame) < 20 || frame[0]>>4 != 4 {
+		return nil, false, errors.New("not an ipv4 frame")
+	}
+	ihl := int(frame[0]&0x0f) * 4
+	if frame[9] != 17 || len(frame) < ihl+8+1 {
+		return nil, false, errors.New("not an udp frame")
+	}
+	toServer = bytes.Equal(frame[12:16], pcapngClientIP)
+	payload = frame[ihl+8:]
+	if payload[0] != 0xFE {
+		return nil, false, errors.New("not a game packet")
+	}
+	return payload[1:], toServer, nil
+}
+
+// ExportPcapng writes every packet in the capture to w as pcapng,
+// the resource pack zip is stored in a custom block so the capture can be imported again
+func ExportPcapng(r *Pcap2Reader, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	var body []byte
+	body = binary.LittleEndian.AppendUint32(body, pcapngByteOrderMagic)
+	body = binary.LittleEndian.AppendUint16(body, 1)
+	body = binary.LittleEndian.AppendUint16(body, 0)
+	body = binary.LittleEndian.AppendUint64(body, math.MaxUint64) // unknown section length
+	body = appendOption(body, pcapngOptShbUserAp, []byte("bedrocktool"))
+	body = appendOptionEnd(body)
+	if err := writePcapngBlock(bw, pcapngBlockSectionHeader, body); err != nil {
+		return err
+	}
+
+	body = body[:0]
+	body = binary.LittleEndian.AppendUint16(body, LinkTypeBedrock)
+	body = binary.LittleEndian.AppendUint16(body, 0)
+	body = binary.LittleEndian.AppendUint32(body, 0)
+	body = appendOption(body, pcapngOptIfName, []byte("bedrock"))
+	body = appendOption(body, pcapngOptIfTsresol, []byte{3})
+	body = appendOptionEnd(body)
+	if err := writePcapngBlock(bw, pcapngBlockInterface, body); err != nil {
+		return err
+	}
+
+	zipSection := r.ZipSection()
+	body = binary.LittleEndian.AppendUint32(body[:0], pcapngPEN)
+	body = append(body, make([]byte, zipSection.Size())...)
+	if _, err := io.ReadFull(zipSection, body[4:]); err != nil {
+		return err
+	}
+	body = appendPadding(body, len(body))
+	if err := writePcapngBlock(bw, pcapngBlockCustom, body); err != nil {
+		return err
+	}
+
+	var frame []byte
+	for {
+		payload, toServer, receivedTime, err := r.ReadRaw()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			return err
+		}
+		frame = appendFrame(frame[:0], toServer, payload)
+
+		var header packet.Header
+		header.Read(bytes.NewBuffer(payload))
+		name := fmt.Sprintf("%d", header.PacketID)
+		if pkFunc, ok := r.pool[header.PacketID]; ok {
+			name = PacketName(pkFunc())
+		}
+
+		ts := uint64(receivedTime.UnixMilli())
+		body = body[:0]
+		body = binary.LittleEndian.AppendUint32(body, 0)
+		body = binary.LittleEndian.AppendUint32(body, uint32(ts>>32))
+		body = binary.LittleEndian.AppendUint32(body, uint32(ts))
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(frame)))
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(frame)))
+		body = append(body, frame...)
+		body = appendPadding(body, len(frame))
+		body = appendOption(body, pcapngOptComment, []byte(name))
+		body = appendOptionEnd(body)
+		if err := writePcapngBlock(bw, pcapngBlockEnhancedPacket, body); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+type pcapngInterface struct {
+	linkType uint16
+	// duration of one timestamp unit
+	resolution time.Duration
+}
+
+func parseTsresol(v byte) time.Duration {
+	if v&0x80 != 0 {
+		return time.Second >> (v & 0x7f)
+	}
+	d := time.Second
+	for range v {
+		d /= 10
+	}
+	return max(d, 1)
+}
+
+// validBlockLength checks that a block is at least its type and both lengths and padded to 4 bytes
+func validBlockLength(blockLength uint32) bool {
+	return blockLength >= 12 && blockLength%4 == 0
+}
+
+// ImportPcapng converts a pcapng that was written by ExportPcapng back into a capture
+func ImportPcapng(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	var interfaces []pcapngInterface
+	var wroteHeader bool
+	var buf []byte
+	var packets int
+
+	writeHeader := func(zipData []byte) error {
+		wroteHeader = true
+		return WriteHeader(bw, bytes.NewReader(zipData), int64(len(zipData)))
+	}
+
+	var head [8]byte
+	for {
+		if _, err := io.ReadFull(br, head[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		blockType := byteOrder.Uint32(head[0:])
+		if blockType == pcapngBlockSectionHeader {
+			// the byte order is only known after reading the magic
+			var magic [4]byte
+			if _, err := io.ReadFull(br, magic[:]); err != nil {
+				return err
+			}
+			switch binary.LittleEndian.Uint32(magic[:]) {
+			case pcapngByteOrderMagic:
+				byteOrder = binary.LittleEndian
+			case 0x4D3C2B1A:
+				byteOrder = binary.BigEndian
+			default:
+				return errors.New("invalid pcapng byte order magic")
+			}
+			interfaces = interfaces[:0]
+			blockLength := byteOrder.Uint32(head[4:])
+			if !validBlockLength(blockLength) {
+				return errors.New("invalid pcapng block length")
+			}
+			if _, err := io.CopyN(io.Discard, br, int64(blockLength)-12); err != nil {
+				return err
+			}
+			continue
+		}
+
+		blockLength := byteOrder.Uint32(head[4:])
+		if !validBlockLength(blockLength) {
+			return errors.New("invalid pcapng block length")
+		}
+		if cap(buf) < int(blockLength)-8 {
+			buf = make([]byte, blockLength-8)
+		}
+		buf = buf[:blockLength-8]
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return err
+		}
+		body := buf[:len(buf)-4]
+
+		switch blockType {
+		case pcapngBlockInterface:
+			if len(body) < 8 {
+				return errors.New("invalid interface description block")
+			}
+			iface := pcapngInterface{
+				linkType:   byteOrder.Uint16(body[0:]),
+				resolution: time.Microsecond,
+			}
+			options := body[8:]
+			for len(options) >= 4 {
+				code := byteOrder.Uint16(options[0:])
+				length := int(byteOrder.Uint16(options[2:]))
+				if code == pcapngOptEnd {
+					break
+				}
+				if len(options) < 4+length {
+					return errors.New("invalid interface description block option")
+				}
+				if code == pcapngOptIfTsresol && length == 1 {
+					iface.resolution = parseTsresol(options[4])
+				}
+				options = options[min(4+length+(4-length%4)%4, len(options)):]
+			}
+			interfaces = append(interfaces, iface)
+
+		case pcapngBlockCustom:
+			if len(body) < 4 || byteOrder.Uint32(body[0:]) != pcapngPEN || wroteHeader {
+				continue
+			}
+			zipData := body[4:]
+			// strip the padding, a zip ends with its end of directory record
+			if i := bytes.LastIndex(zipData, []byte("PK\x05\x06")); i >= 0 && len(zipData) >= i+22 {
+				zipData = zipData[:i+22+int(binary.LittleEndian.Uint16(zipData[i+20:]))]
+			}
+			if err := writeHeader(zipData); err != nil {
+				return err
+			}
+
+		case pcapngBlockEnhancedPacket:
+			if len(body) < 20 {
+				return errors.New("invalid enhanced packet block")
+			}
+			interfaceID := byteOrder.Uint32(body[0:])
+			if int(interfaceID) >= len(interfaces) {
+				return fmt.Errorf("packet on unknown interface %d", interfaceID)
+			}
+			iface := interfaces[interfaceID]
+			switch iface.linkType {
+			case LinkTypeBedrock, linkTypeRaw, linkTypeIPv4:
+			default:
+				continue
+			}
+
+			ts := uint64(byteOrder.Uint32(body[4:]))<<32 | uint64(byteOrder.Uint32(body[8:]))
+			capturedLength := byteOrder.Uint32(body[12:])
+			if len(body) < 20+int(capturedLength) {
+				return errors.New("invalid enhanced packet block")
+			}
+			payload, toServer, err := parseFrame(body[20 : 20+capturedLength])
+			if err != nil {
+				logrus.Debugf("skipping frame: %s", err)
+				continue
+			}
+
+			if !wroteHeader {
+				logrus.Warn("pcapng has no resource pack block, the capture will not contain any packs")
+				if err := writeHeader(EmptyZip()); err != nil {
+					return err
+				}
+			}
+			receivedTime := time.Unix(0, int64(ts)*int64(iface.resolution))
+			if _, err := bw.Write(AppendPacket(nil, toServer, payload, receivedTime)); err != nil {
+				return err
+			}
+			packets++
+		}
+	}
+
+	if !wroteHeader {
+		if err := writeHeader(EmptyZip()); err != nil {
+			return err
+		}
+	}
+	logrus.Infof("Imported %d packets", packets)
+	return bw.Flush()
+}
@@ -0,0 +1,131 @@
+package pcap2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// openTestCapture writes a capture without resource packs that has the frames in it and opens it
+func openTestCapture(t *testing.T, frames []byte) *Pcap2Reader {
+	zipData := EmptyZip()
+	var data bytes.Buffer
+	if err := WriteHeader(&data, bytes.NewReader(zipData), int64(len(zipData))); err != nil {
+		t.Fatal(err)
+	}
+	data.Write(frames)
+	return openTestCaptureData(t, data.Bytes())
+}
+
+func openTestCaptureData(t *testing.T, data []byte) *Pcap2Reader {
+	path := filepath.Join(t.TempDir(), "test.pcap2")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	r, err := NewPcap2Reader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestPcapngRoundTrip(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	type test struct {
+		id       uint32
+		toServer bool
+		time     time.Time
+	}
+	var tests = []test{
+		{id: packet.IDStartGame, toServer: false, time: start},
+		{id: packet.IDPlayerAuthInput, toServer: true, time: start.Add(50 * time.Millisecond)},
+		{id: packet.IDLevelChunk, toServer: false, time: start.Add(time.Hour + 7*time.Millisecond)},
+	}
+	var frames []byte
+	for _, tt := range tests {
+		frames = appendTestPacket(frames, tt.id, tt.toServer, tt.time)
+	}
+
+	var pcapng bytes.Buffer
+	if err := ExportPcapng(openTestCapture(t, frames), &pcapng); err != nil {
+		t.Fatal(err)
+	}
+	var imported bytes.Buffer
+	if err := ImportPcapng(&pcapng, &imported); err != nil {
+		t.Fatal(err)
+	}
+
+	r := openTestCaptureData(t, imported.Bytes())
+	for i, tt := range tests {
+		payload, toServer, receivedTime, err := r.ReadRaw()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var header packet.Header
+		buf := bytes.NewBuffer(payload)
+		if err := header.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+		if header.PacketID != tt.id || toServer != tt.toServer || !receivedTime.Equal(tt.time) || !bytes.Equal(buf.Bytes(), []byte{1, 2, 3}) {
+			t.Fatalf("packet %d expected: %d %v %s\ngot: %d %v %s %v\n", i, tt.id, tt.toServer, tt.time, header.PacketID, toServer, receivedTime, buf.Bytes())
+		}
+	}
+	if _, _, _, err := r.ReadRaw(); err != net.ErrClosed {
+		t.Fatalf("expected the end of the capture, got %v", err)
+	}
+}
+
+func TestImportPcapngInvalid(t *testing.T) {
+	var sectionHeader []byte
+	sectionHeader = binary.LittleEndian.AppendUint32(sectionHeader, pcapngByteOrderMagic)
+	sectionHeader = binary.LittleEndian.AppendUint16(sectionHeader, 1)
+	sectionHeader = binary.LittleEndian.AppendUint16(sectionHeader, 0)
+	sectionHeader = binary.LittleEndian.AppendUint64(sectionHeader, 0)
+
+	withInterface := func(iface []byte) []byte {
+		var data bytes.Buffer
+		writePcapngBlock(&data, pcapngBlockSectionHeader, sectionHeader)
+		writePcapngBlock(&data, pcapngBlockInterface, iface)
+		return data.Bytes()
+	}
+	withSectionLength := func(blockLength uint32) []byte {
+		var data []byte
+		data = binary.LittleEndian.AppendUint32(data, pcapngBlockSectionHeader)
+		data = binary.LittleEndian.AppendUint32(data, blockLength)
+		data = binary.LittleEndian.AppendUint32(data, pcapngByteOrderMagic)
+		return append(data, make([]byte, 32)...)
+	}
+
+	var tests = map[string][]byte{
+		"empty interface":             withInterface([]byte{}),
+		"short interface":             withInterface([]byte{LinkTypeBedrock, 0, 0, 0}),
+		"option past the end":         withInterface(append([]byte{LinkTypeBedrock, 0, 0, 0, 0, 0, 0, 0}, pcapngOptIfTsresol, 0, 8, 0, 3)),
+		"short section header":        withSectionLength(8),
+		"unaligned section header":    withSectionLength(30),
+		"section header past the end": withSectionLength(1 << 20),
+	}
+	for name, data := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("%s: panic %v", name, r)
+				}
+			}()
+			if err := ImportPcapng(bytes.NewReader(data), io.Discard); err == nil {
+				t.Fatalf("%s should not import", name)
+			}
+		}()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net"
 	"os"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -17,8 +18,9 @@ import (
 )
 
 type ReplayConnector struct {
-	reader *Pcap2Reader
-	f      *os.File
+	reader   *Pcap2Reader
+	f        *os.File
+	filename string
 
 	ctx       context.Context
 	cancelCtx context.CancelCauseFunc
@@ -26,7 +28,20 @@ type ReplayConnector struct {
 	spawn chan struct{}
 
 	expectedIDs     atomic.Value
-	deferredPackets []packet.Packet
+	deferredPackets []replayPacket
+	lastToServer    bool
+
+	// pacing, speed 0 sends packets as fast as they can be read
+	paceMu      sync.Mutex
+	speed       float64
+	paused      bool
+	paceChanged chan struct{}
+	// the replay reached packetStart at wallStart
+	packetStart time.Time
+	wallStart   time.Time
+	lastPacket  time.Time
+	// funcs that have to run on the reading goroutine while it waits
+	loopFuncs <-chan func()
 
 	clientData login.ClientData
 	gameData   minecraft.GameData
@@ -34,11 +49,19 @@ type ReplayConnector struct {
 	resourcePackHandler *resourcepacks.ResourcePackHandler
 }
 
+type replayPacket struct {
+	pk           packet.Packet
+	toServer     bool
+	timeReceived time.Time
+}
+
 type PacketFunc func(header packet.Header, payload []byte, src, dst net.Addr, timeReceived time.Time)
 
 func CreateReplayConnector(ctx context.Context, filename string, packetFunc PacketFunc, resourcePackHandler *resourcepacks.ResourcePackHandler) (r *ReplayConnector, err error) {
 	r = &ReplayConnector{
 		spawn:               make(chan struct{}),
+		paceChanged:         make(chan struct{}),
+		filename:            filename,
 		resourcePackHandler: resourcePackHandler,
 	}
 	if r.resourcePackHandler != nil {
@@ -131,11 +154,10 @@ func (r *ReplayConnector) handleLoginSequence(pk packet.Packet) (bool, bool, err
 func (r *ReplayConnector) ReadUntilLogin() error {
 	gameStarted := false
 	for !gameStarted {
-		pk, _, timeReceived, err := r.reader.ReadPacket(false)
+		pk, toServer, timeReceived, err := r.reader.ReadPacket(false)
 		if err != nil {
 			return err
 		}
-		_ = timeReceived
 
 		var handled bool
 		gameStarted, handled, err = r.handleLoginSequence(pk)
@@ -143,12 +165,143 @@ func (r *ReplayConnector) ReadUntilLogin() error {
 			return err
 		}
 		if !handled {
-			r.deferredPackets = append(r.deferredPackets, pk)
+			r.deferredPackets = append(r.deferredPackets, replayPacket{pk, toServer, timeReceived})
 		}
 	}
 	return nil
 }
 
+// SkipTo moves the replay forward to offset after the first packet in the capture,
+// it has to be called after ReadUntilLogin so the login sequence is still handled
+func (r *ReplayConnector) SkipTo(offset time.Duration) error {
+	if err := r.reader.OpenIndex(r.filename); err != nil {
+		return err
+	}
+	start, err := r.reader.StartTime()
+	if err != nil {
+		return err
+	}
+	packetNumber, err := r.reader.PacketAt(start.Add(offset))
+	if err != nil {
+		return err
+	}
+	if packetNumber <= r.reader.CurrentPacket {
+		return nil
+	}
+	logrus.Infof("Skipping to packet %d (%s)", packetNumber, offset)
+	if err = r.reader.Seek(packetNumber); err != nil {
+		return err
+	}
+	r.paceMu.Lock()
+	r.packetStart = time.Time{}
+	r.paceMu.Unlock()
+	return nil
+}
+
+// SetSpeed makes packets get sent at their recorded times sped up by speed,
+// 0 sends them as fast as possible
+func (r *ReplayConnector) SetSpeed(speed float64) {
+	r.paceMu.Lock()
+	defer r.paceMu.Unlock()
+	r.speed = max(speed, 0)
+	r.resetPace()
+}
+
+func (r *ReplayConnector) Speed() float64 {
+	r.paceMu.Lock()
+	defer r.paceMu.Unlock()
+	return r.speed
+}
+
+// Pause stops packets from being sent until Resume is called
+func (r *ReplayConnector) Pause() {
+	r.paceMu.Lock()
+	defer r.paceMu.Unlock()
+	r.paused = true
+	r.resetPace()
+}
+
+func (r *ReplayConnector) Resume() {
+	r.paceMu.Lock()
+	defer r.paceMu.Unlock()
+	r.paused = false
+	r.resetPace()
+}
+
+func (r *ReplayConnector) Paused() bool {
+	r.paceMu.Lock()
+	defer r.paceMu.Unlock()
+	return r.paused
+}
+
+// LastToServer returns if the last read packet was sent by the client in the capture
+func (r *ReplayConnector) LastToServer() bool {
+	return r.lastToServer
+}
+
+// SetLoopFuncs makes the replay run funcs from ch while it waits to send a packet,
+// a command that resumes a paused replay could never run otherwise
+func (r *ReplayConnector) SetLoopFuncs(ch <-chan func()) {
+	r.loopFuncs = ch
+}
+
+// resetPace continues pacing from the last sent packet, paceMu has to be held
+func (r *ReplayConnector) resetPace() {
+	r.packetStart = r.lastPacket
+	r.wallStart = time.Now()
+	close(r.paceChanged)
+	r.paceChanged = make(chan struct{})
+}
+
+// waitPace blocks until a packet received at receivedTime should be sent
+func (r *ReplayConnector) waitPace(receivedTime time.Time) error {
+	for {
+		r.paceMu.Lock()
+		changed := r.paceChanged
+		if !r.paused && r.speed <= 0 {
+			r.lastPacket = receivedTime
+			r.paceMu.Unlock()
+			return nil
+		}
+		var wait time.Duration
+		if !r.paused {
+			if r.packetStart.IsZero() {
+				r.packetStart = receivedTime
+				r.wallStart = time.Now()
+			}
+			target := r.wallStart.Add(time.Duration(float64(receivedTime.Sub(r.packetStart)) / r.speed))
+			wait = time.Until(target)
+			if wait <= 0 {
+				r.lastPacket = receivedTime
+				r.paceMu.Unlock()
+				return nil
+			}
+		}
+		r.paceMu.Unlock()
+
+		// paused waits on changed only, a nil channel never receives
+		var timeout <-chan time.Time
+		var timer *time.Timer
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-timeout:
+		case <-changed:
+		case fn := <-r.loopFuncs:
+			fn()
+		case <-r.ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if r.ctx.Err() != nil {
+			return net.ErrClosed
+		}
+	}
+}
+
 func (r *ReplayConnector) Context() context.Context {
 	return r.ctx
 }
@@ -231,17 +384,22 @@ func (r *ReplayConnector) ReadPacketWithTime() (pk packet.Packet, receivedAt tim
 	}
 
 	if len(r.deferredPackets) > 0 {
-		pk = r.deferredPackets[0]
+		deferred := r.deferredPackets[0]
 		r.deferredPackets = r.deferredPackets[1:]
-		receivedAt = time.Now() // fixme
-		return
+		r.lastToServer = deferred.toServer
+		return deferred.pk, deferred.timeReceived, nil
 	}
 
 	pk, toServer, receivedTime, err := r.reader.ReadPacket(false)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
-	_ = toServer // proxy puts both from client and from server packets into the same callback so doesnt matter
+	if err = r.waitPace(receivedTime); err != nil {
+		return nil, time.Time{}, err
+	}
+	// proxy puts both from client and from server packets into the same callback,
+	// only a watching client cares about the direction
+	r.lastToServer = toServer
 	return pk, receivedTime, nil
 }
 
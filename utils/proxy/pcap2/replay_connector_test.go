@@ -0,0 +1,100 @@
+package pcap2
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestReplay(t *testing.T) *ReplayConnector {
+	r := &ReplayConnector{paceChanged: make(chan struct{})}
+	r.ctx, r.cancelCtx = context.WithCancelCause(context.Background())
+	t.Cleanup(func() { r.cancelCtx(nil) })
+	return r
+}
+
+// waitPaceAsync runs waitPace and returns a channel that gets its result
+func waitPaceAsync(r *ReplayConnector, receivedTime time.Time) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- r.waitPace(receivedTime) }()
+	return done
+}
+
+func TestWaitPaceSpeed(t *testing.T) {
+	r := newTestReplay(t)
+	start := time.UnixMilli(1700000000000)
+
+	// speed 0 doesnt wait at all
+	began := time.Now()
+	for i := range 10 {
+		if err := r.waitPace(start.Add(time.Duration(i) * time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(began); elapsed > 50*time.Millisecond {
+		t.Fatalf("speed 0 took %s", elapsed)
+	}
+
+	// pacing continues from the last packet, 400ms recorded at double speed take 200ms
+	r.SetSpeed(2)
+	began = time.Now()
+	if err := r.waitPace(start.Add(9*time.Hour + 400*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(began); elapsed < 180*time.Millisecond || elapsed > 350*time.Millisecond {
+		t.Fatalf("expected 200ms at double speed, took %s", elapsed)
+	}
+}
+
+func TestWaitPacePause(t *testing.T) {
+	r := newTestReplay(t)
+	loopFuncs := make(chan func())
+	r.SetLoopFuncs(loopFuncs)
+	start := time.UnixMilli(1700000000000)
+	r.SetSpeed(1)
+	if err := r.waitPace(start); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Pause()
+	done := waitPaceAsync(r, start.Add(time.Millisecond))
+	select {
+	case err := <-done:
+		t.Fatalf("paused replay sent a packet, %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// funcs from the loop still run while paused
+	ran := make(chan struct{})
+	select {
+	case loopFuncs <- func() { close(ran) }:
+	case <-time.After(time.Second):
+		t.Fatal("paused replay doesnt run loop funcs")
+	}
+	<-ran
+
+	// the pause doesnt count as replay time, the next packet is due 1ms after resuming
+	r.Resume()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("resumed replay didnt send the packet")
+	}
+
+	r.Pause()
+	done = waitPaceAsync(r, start.Add(2*time.Millisecond))
+	r.cancelCtx(nil)
+	select {
+	case err := <-done:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("expected net.ErrClosed after closing, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("closing didnt stop waiting")
+	}
+}
@@ -0,0 +1,86 @@
+package pcap2
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+)
+
+// Version is the capture format version that gets written
+const Version = 5
+
+// AppendPacket appends the framing and compressed payload of a packet to buf
+func AppendPacket(buf []byte, toServer bool, payload []byte, timeReceived time.Time) []byte {
+	payloadCompressed := s2.EncodeBetter(nil, payload)
+
+	buf = append(buf, 0xAA, 0xAA, 0xAA, 0xAA)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(payloadCompressed)))
+	if toServer {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(timeReceived.UnixMilli()))
+	buf = append(buf, payloadCompressed...)
+	buf = append(buf, 0xBB, 0xBB, 0xBB, 0xBB)
+	return buf
+}
+
+// SetFrameTime changes the receive time of a frame from AppendPacket
+func SetFrameTime(frame []byte, timeReceived time.Time) {
+	binary.LittleEndian.PutUint64(frame[9:], uint64(timeReceived.UnixMilli()))
+}
+
+// WriteHeader writes the capture header followed by the resource pack zip,
+// the zip has to have been written with an offset of 16
+func WriteHeader(w io.Writer, zipData io.Reader, zipSize int64) error {
+	head := []byte("BTCP")
+	head = binary.LittleEndian.AppendUint32(head, Version)
+	head = binary.LittleEndian.AppendUint64(head, uint64(zipSize))
+	if _, err := w.Write(head); err != nil {
+		return err
+	}
+	_, err := io.CopyN(w, zipData, zipSize)
+	return err
+}
+
+// EmptyZip returns a pack zip without any packs in it for WriteHeader
+func EmptyZip() []byte {
+	buf := bytes.NewBuffer(nil)
+	z := zip.NewWriter(buf)
+	z.SetOffset(16)
+	z.Close()
+	return buf.Bytes()
+}
+
+// MergePackZips combines the pack zips of several captures for WriteHeader,
+// packs that are in more than one of them are only kept once
+func MergePackZips(readers ...*Pcap2Reader) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	z := zip.NewWriter(buf)
+	z.SetOffset(16)
+	seen := make(map[string]bool)
+	for _, r := range readers {
+		zr, err := zip.NewReader(io.NewSectionReader(r.f, 0, r.packetsStart), r.packetsStart)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if seen[f.Name] {
+				continue
+			}
+			seen[f.Name] = true
+			if err = z.Copy(f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
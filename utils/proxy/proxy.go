@@ -17,11 +17,19 @@ import (
 type ProxySettings struct {
 	ConnectInfo *connectinfo.ConnectInfo `opt:"Address" flag:"address" desc:"locale.remote_address"`
 
-	Debug         bool   `opt:"Debug" flag:"debug" desc:"locale.debug_mode"`
-	ExtraDebug    bool   `opt:"Extra Debug" flag:"extra-debug" desc:"extra debug info (packet.log)"`
-	Capture       bool   `opt:"Packet Capture" flag:"capture" default:"true" desc:"Capture pcap2 file"`
-	ClientCache   bool   `opt:"Client Cache" flag:"client-cache" default:"true" desc:"Enable Client Cache"`
-	ListenAddress string `opt:"Listen Address" flag:"listen" default:"0.0.0.0:19132" desc:"example :19132 or 127.0.0.1:19132"`
+	Debug         bool     `opt:"Debug" flag:"debug" desc:"locale.debug_mode"`
+	ExtraDebug    bool     `opt:"Extra Debug" flag:"extra-debug" desc:"extra debug info (packet.log)"`
+	Capture       bool     `opt:"Packet Capture" flag:"capture" default:"true" desc:"Capture pcap2 file"`
+	ClientCache   bool     `opt:"Client Cache" flag:"client-cache" default:"true" desc:"Enable Client Cache"`
+	ListenAddress string   `opt:"Listen Address" flag:"listen" default:"0.0.0.0:19132" desc:"example :19132 or 127.0.0.1:19132"`
+	ReplayStart   string   `opt:"Replay Start" flag:"replay-start" desc:"when replaying a capture, skip to this offset into it (e.g. 40m)"`
+	ReplaySpeed   string   `opt:"Replay Speed" flag:"replay-speed" desc:"when replaying a capture, send packets at their recorded times sped up by this (e.g. 1 or 2.5), as fast as possible if empty"`
+	ReplayClient  bool     `opt:"Replay Client" flag:"replay-client" desc:"when replaying a capture, let a minecraft client join to watch it"`
+	Handlers      []string `opt:"Handlers" flag:"handlers" desc:"extra handlers to run in the same session seperated by comma (e.g. worlds,skins,chat)"`
+	Rules         string   `opt:"Rules" flag:"rules" type:"file" desc:"yaml or json file with rules that drop, delay, modify or inject packets"`
+	Script        string   `opt:"Script" flag:"script" type:"file,js" desc:"path to a .js or .ts script, or a folder with an index, that can watch and send packets and add commands, reloaded when changed. what it may do is set in name.permissions.json next to it"`
+	Spectators    int      `opt:"Spectators" flag:"spectators" desc:"how many extra clients can join to watch the session"`
+	Reconnect     int      `opt:"Reconnect" flag:"reconnect" desc:"how many times in a row to reconnect when the server connection is lost, -1 for no limit"`
 }
 
 type PacketFunc func(header packet.Header, payload []byte, src, dst net.Addr, timeReceived time.Time)
@@ -31,6 +39,8 @@ type ingameCommand struct {
 }
 
 var NewPacketCapturer func() *Handler
+var NewRulesHandler func(filename string) (func() *Handler, error)
+var NewScriptHandler func(filename string) (func() *Handler, error)
 
 var errCancelConnect = fmt.Errorf("cancelled connecting")
 
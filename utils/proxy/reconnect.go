@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	reconnectMinDelay = 2 * time.Second
+	reconnectMaxDelay = 2 * time.Minute
+	// a session that stayed connected this long starts the backoff over
+	reconnectResetAfter = 5 * time.Minute
+)
+
+type errReconnect struct {
+	cause error
+}
+
+func (e *errReconnect) Error() string {
+	return fmt.Sprintf("reconnecting after: %s", e.cause)
+}
+
+// reconnector keeps the backoff between the sessions of one connection
+type reconnector struct {
+	ctx context.Context
+	// how many attempts in a row, -1 for no limit
+	attempts int
+	attempt  int
+}
+
+// next returns how long to wait before connecting again, false if there are no attempts left
+func (r *reconnector) next(connectedFor time.Duration) (time.Duration, bool) {
+	if connectedFor > reconnectResetAfter {
+		r.attempt = 0
+	}
+	if r.attempts >= 0 && r.attempt >= r.attempts {
+		return 0, false
+	}
+	delay := min(reconnectMinDelay<<min(r.attempt, 7), reconnectMaxDelay)
+	r.attempt++
+	return delay, true
+}
+
+// wait holds the client with a countdown until the next attempt
+func (r *reconnector) wait(s *Session, delay time.Duration) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	deadline := time.Now().Add(delay)
+	for left := delay; left > 0; left = time.Until(deadline) {
+		s.SendPopup(fmt.Sprintf("§eConnection lost, reconnecting in %ds §7(attempt %d)", int(left.Round(time.Second).Seconds()), r.attempt))
+		select {
+		case <-r.ctx.Done():
+			return r.ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Reconnecting is true when the session ended because the server connection was lost and it will connect again
+func (s *Session) Reconnecting() bool {
+	return s.reconnecting
+}
+
+// Resumed is true when this session continues one that lost its connection to the server
+func (s *Session) Resumed() bool {
+	return s.resumed
+}
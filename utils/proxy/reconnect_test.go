@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/connectinfo"
+)
+
+func TestReconnectorNext(t *testing.T) {
+	r := &reconnector{attempts: 3}
+	type test struct {
+		connectedFor time.Duration
+		delay        time.Duration
+		ok           bool
+	}
+	var tests = []test{
+		{connectedFor: 0, delay: reconnectMinDelay, ok: true},
+		{connectedFor: 0, delay: 2 * reconnectMinDelay, ok: true},
+		{connectedFor: 0, delay: 4 * reconnectMinDelay, ok: true},
+		{connectedFor: 0, ok: false},
+		// a session that stayed connected for a while starts over
+		{connectedFor: time.Hour, delay: reconnectMinDelay, ok: true},
+	}
+	for i, tt := range tests {
+		delay, ok := r.next(tt.connectedFor)
+		if delay != tt.delay || ok != tt.ok {
+			t.Fatalf("%d expected: %s %v\ngot: %s %v\n", i, tt.delay, tt.ok, delay, ok)
+		}
+	}
+}
+
+func TestReconnectRetryFailsOnce(t *testing.T) {
+	p := &Context{ctx: t.Context(), settings: ProxySettings{Reconnect: 3}}
+	var resumed []bool
+	p.runSession = func(s *Session) error {
+		resumed = append(resumed, s.resumed)
+		switch len(resumed) {
+		case 1:
+			// lost the server, what holdForReconnect ends with
+			s.lostServer = errors.New("connection lost")
+			s.reconnecting = true
+			return &errReconnect{cause: s.lostServer}
+		case 2:
+			// the server isnt back yet
+			return s.retryResume(errors.New("dial failed"))
+		}
+		return nil
+	}
+
+	if err := p.connect(&connectinfo.ConnectInfo{Value: "127.0.0.1:19132"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []bool{false, true, true}; !slices.Equal(resumed, expected) {
+		t.Fatalf("sessions expected resumed: %v\ngot: %v\n", expected, resumed)
+	}
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+type registeredHandler struct {
+	// Name of the handler it makes, to find it when the command already added one
+	handlerName string
+	newHandler  func(ctx context.Context) *Handler
+}
+
+var registeredHandlers = map[string]registeredHandler{}
+
+// RegisterHandler makes a handler available to the -handlers flag of every proxy command,
+// newHandler is called once for every session that doesnt have a handler named handlerName yet
+func RegisterHandler(name, handlerName string, newHandler func(ctx context.Context) *Handler) {
+	registeredHandlers[name] = registeredHandler{handlerName, newHandler}
+}
+
+// RegisteredHandlers returns the names of all registered handlers
+func RegisteredHandlers() []string {
+	return slices.Sorted(maps.Keys(registeredHandlers))
+}
+
+func checkHandlerNames(names []string) error {
+	for _, name := range names {
+		if _, ok := registeredHandlers[name]; !ok {
+			return fmt.Errorf("unknown handler %s, available: %s", name, strings.Join(RegisteredHandlers(), ", "))
+		}
+	}
+	return nil
+}
+
+// addRegisteredHandlers adds the named handlers to the session,
+// handlers the command already added are only given the name
+func (s *Session) addRegisteredHandlers(ctx context.Context, names []string) {
+	for _, name := range names {
+		registered := registeredHandlers[name]
+		if existing := s.handlers.find(registered.handlerName); existing != nil {
+			existing.key = name
+			continue
+		}
+		handler := registered.newHandler(ctx)
+		handler.key = name
+		s.handlers = append(s.handlers, handler)
+	}
+}
+
+func (s *Session) addHandlerCommand() {
+	s.AddCommand(func(args []string) bool {
+		if len(args) == 0 {
+			for _, handler := range s.handlers {
+				state := "§aon"
+				if !handler.Enabled() {
+					state = "§coff"
+				}
+				name := handler.Name
+				if handler.key != "" {
+					name = handler.key
+				}
+				s.SendMessage(fmt.Sprintf("%s: %s", name, state))
+			}
+			return true
+		}
+
+		handler := s.handlers.find(args[0])
+		if handler == nil {
+			s.SendMessage(fmt.Sprintf("No handler %s in this session", args[0]))
+			return false
+		}
+		enabled := !handler.Enabled()
+		if len(args) > 1 {
+			switch args[1] {
+			case "on":
+				enabled = true
+			case "off":
+				enabled = false
+			default:
+				s.SendMessage("Has to be on or off")
+				return false
+			}
+		}
+		handler.SetEnabled(enabled)
+		if enabled {
+			s.SendMessage(fmt.Sprintf("Enabled %s", args[0]))
+		} else {
+			s.SendMessage(fmt.Sprintf("Disabled %s", args[0]))
+		}
+		return true
+	}, protocol.Command{
+		Name:        "handler",
+		Description: "list handlers, or switch one on or off",
+	})
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func TestAddRegisteredHandlers(t *testing.T) {
+	var made int
+	RegisterHandler("testhandler", "Test Handler", func(ctx context.Context) *Handler {
+		made++
+		return &Handler{Name: "Test Handler"}
+	})
+	defer delete(registeredHandlers, "testhandler")
+
+	if err := checkHandlerNames([]string{"testhandler"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkHandlerNames([]string{"testhandler", "missing"}); err == nil {
+		t.Fatal("unknown handler name was accepted")
+	}
+
+	s := NewSession(t.Context(), ProxySettings{}, nil, nil, false)
+	s.addRegisteredHandlers(t.Context(), []string{"testhandler"})
+	if made != 1 || len(s.handlers) != 1 || s.handlers.find("testhandler") == nil {
+		t.Fatalf("expected one new handler, made %d, have %d", made, len(s.handlers))
+	}
+
+	// the command already added it, it only gets the registered name
+	existing := &Handler{Name: "Test Handler"}
+	s = NewSession(t.Context(), ProxySettings{}, nil, nil, false)
+	s.handlers = Handlers{existing}
+	s.addRegisteredHandlers(t.Context(), []string{"testhandler"})
+	if made != 1 || len(s.handlers) != 1 || s.handlers.find("testhandler") != existing {
+		t.Fatalf("existing handler was not reused, made %d, have %d", made, len(s.handlers))
+	}
+	if s.handlers.find("testhandler ") != existing || s.handlers.find("TESTHANDLER") != existing {
+		t.Fatal("find should ignore case and spaces")
+	}
+}
+
+func TestHandlerEnabled(t *testing.T) {
+	var calls []string
+	newHandler := func(name string) *Handler {
+		return &Handler{
+			Name: name,
+			PacketCallback: func(s *Session, pk packet.Packet, toServer bool, timeReceived time.Time, preLogin bool) (packet.Packet, error) {
+				calls = append(calls, name)
+				return pk, nil
+			},
+		}
+	}
+	a, b := newHandler("a"), newHandler("b")
+	handlers := Handlers{a, b}
+
+	b.SetEnabled(false)
+	if b.Enabled() {
+		t.Fatal("b is still enabled")
+	}
+	pk, err := handlers.PacketCallback(nil, &packet.SetTime{}, false, time.Now(), false)
+	if err != nil || pk == nil {
+		t.Fatalf("packet was dropped, %v", err)
+	}
+	if len(calls) != 1 || calls[0] != "a" {
+		t.Fatalf("disabled handler was called, calls %v", calls)
+	}
+
+	calls = nil
+	b.SetEnabled(true)
+	_, _ = handlers.PacketCallback(nil, &packet.SetTime{}, false, time.Now(), false)
+	if len(calls) != 2 {
+		t.Fatalf("enabled handler was not called, calls %v", calls)
+	}
+}
@@ -33,9 +33,12 @@ type Session struct {
 	log       *logrus.Entry
 	ctx       context.Context
 	cancelCtx context.CancelCauseFunc
-	commands  map[string]ingameCommand
 	settings  ProxySettings
 
+	// handlers and scripts add commands from other goroutines
+	commandsMu sync.Mutex
+	commands   map[string]ingameCommand
+
 	// from proxy
 	addedPacks  []resource.Pack
 	withClient  bool
@@ -45,8 +48,9 @@ type Session struct {
 	packetLogger       *packetLogger
 	packetLoggerClient *packetLogger
 
-	listener  *minecraft.Listener
-	blobCache *blobcache.Blobcache
+	listener   *minecraft.Listener
+	blobCache  *blobcache.Blobcache
+	spectators *spectators
 
 	Server minecraft.IConn
 	Client minecraft.IConn
@@ -61,6 +65,20 @@ type Session struct {
 	spawned          bool
 	disconnectReason string
 	lastPacketTime   atomic.Pointer[time.Time]
+
+	// funcs from other goroutines that run on the loop of server packets, between two packets
+	loopFuncs chan func()
+
+	// gameData the client was started with, closes started once the session is running
+	gameData    minecraft.GameData
+	started     chan struct{}
+	connectedAt time.Time
+
+	// set when the server connection drops without being asked to
+	lostServer   error
+	reconnector  *reconnector
+	reconnecting bool
+	resumed      bool
 }
 
 func NewSession(ctx context.Context, settings ProxySettings, addedPacks []resource.Pack, connectInfo *connectinfo.ConnectInfo, withClient bool) *Session {
@@ -76,8 +94,70 @@ func NewSession(ctx context.Context, settings ProxySettings, addedPacks []resour
 
 		clientConnecting: make(chan struct{}),
 		haveClientData:   make(chan struct{}),
+		started:          make(chan struct{}),
+		spectators:       newSpectators(),
 		disconnectReason: "Connection Lost",
 		commands:         make(map[string]ingameCommand),
+		loopFuncs:        make(chan func()),
+	}
+}
+
+// runOnLoop runs fn on the loop that handles server packets so it doesnt race with the handlers,
+// between two packets or while a replay waits for the next one. returns false if the session ended first
+func (s *Session) runOnLoop(fn func()) bool {
+	done := make(chan struct{})
+	select {
+	case s.loopFuncs <- func() {
+		defer close(done)
+		fn()
+	}:
+	case <-s.ctx.Done():
+		return false
+	}
+	<-done
+	return true
+}
+
+// ReinjectPacket sends a packet a handler held back, on the packet loop after the handlers that come after from,
+// it blocks until the packet was sent so it should be called from its own goroutine
+func (s *Session) ReinjectPacket(from *Handler, pk packet.Packet, toServer bool) {
+	s.runOnLoop(func() {
+		pk, err := s.handlers.after(from).PacketCallback(s, pk, toServer, s.Now(), false)
+		if err != nil {
+			s.log.WithError(err).Warnf("reinjecting %T", pk)
+			return
+		}
+		if pk == nil {
+			return
+		}
+		if toServer {
+			if s.Server != nil {
+				err = s.Server.WritePacket(pk)
+			}
+		} else {
+			err = s.ClientWritePacket(pk)
+			if s.settings.Spectators > 0 {
+				s.spectators.observe(pk)
+				if mirrored(pk) {
+					s.spectators.broadcast(pk)
+				}
+			}
+		}
+		if err != nil {
+			s.log.WithError(err).Warnf("reinjecting %T", pk)
+		}
+	})
+}
+
+// runLoopFuncs runs what was queued with runOnLoop
+func (s *Session) runLoopFuncs() {
+	for {
+		select {
+		case fn := <-s.loopFuncs:
+			fn()
+		default:
+			return
+		}
 	}
 }
 
@@ -89,6 +169,8 @@ func (s *Session) Now() time.Time {
 func (s *Session) AddCommand(exec func([]string) bool, cmd protocol.Command) {
 	cmd.AliasesOffset = 0xffffffff
 	cmd.PermissionLevel = protocol.CommandPermissionLevelAny
+	s.commandsMu.Lock()
+	defer s.commandsMu.Unlock()
 	s.commands[cmd.Name] = ingameCommand{exec, cmd}
 }
 
@@ -193,6 +275,18 @@ func (s *Session) Run() error {
 		defer s.packetLoggerClient.Close()
 	}
 
+	// the listener is made while connecting, a client that is held for reconnecting was already transferred
+	defer func() {
+		if s.listener == nil {
+			return
+		}
+		if s.Client != nil && !s.reconnecting {
+			_ = s.listener.Disconnect(s.Client.(*minecraft.Conn), s.disconnectReason)
+		}
+		_ = s.listener.Close()
+		s.spectators.closeAll(s.disconnectReason)
+	}()
+
 	if s.connectInfo.IsReplay() {
 		replayName, err := s.connectInfo.Address(s.ctx)
 		if err != nil {
@@ -204,26 +298,45 @@ func (s *Session) Run() error {
 			return err
 		}
 		s.Server = replay
+		replay.SetLoopFuncs(s.loopFuncs)
 		err = replay.ReadUntilLogin()
 		if err != nil {
 			return err
 		}
+		if s.settings.ReplayStart != "" {
+			offset, err := time.ParseDuration(s.settings.ReplayStart)
+			if err != nil {
+				return err
+			}
+			if err = replay.SkipTo(offset); err != nil {
+				return err
+			}
+		}
+		speed := 0.0
+		if s.settings.ReplaySpeed != "" {
+			speed, err = strconv.ParseFloat(s.settings.ReplaySpeed, 64)
+			if err != nil {
+				return fmt.Errorf("invalid replay speed: %w", err)
+			}
+		} else if s.settings.ReplayClient {
+			// a watching client wants to see it in real time
+			speed = 1
+		}
+		replay.SetSpeed(speed)
+		s.addReplayCommands(replay)
+		if s.settings.ReplayClient {
+			if err = s.listenReplayClient(replay.ResourcePacks()); err != nil {
+				return err
+			}
+		}
 	} else {
 		if err = s.connect(); err != nil {
-			return err
+			return s.retryResume(err)
 		}
 	}
 	if s.Server != nil {
 		defer s.Server.Close()
 	}
-	if s.listener != nil {
-		defer func() {
-			if s.Client != nil {
-				_ = s.listener.Disconnect(s.Client.(*minecraft.Conn), s.disconnectReason)
-			}
-			_ = s.listener.Close()
-		}()
-	}
 
 	if s.ctx.Err() != nil {
 		err := context.Cause(s.ctx)
@@ -253,6 +366,12 @@ func (s *Session) Run() error {
 
 	gameData := s.Server.GameData()
 	s.handlers.GameDataModifier(s, &gameData)
+	if s.connectInfo.IsReplay() && s.Client != nil {
+		// the watching client isnt the recorded player so it can look around freely
+		gameData.PlayerGameMode = packet.GameTypeSpectator
+	}
+	s.gameData = gameData
+	s.spectators.dimension = gameData.Dimension
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -288,7 +407,7 @@ func (s *Session) Run() error {
 		if s.expectDisconnect {
 			return nil
 		}
-		return err
+		return s.retryResume(err)
 	}
 
 	if s.handlers.OnConnect(s) {
@@ -298,6 +417,11 @@ func (s *Session) Run() error {
 	messages.SendEvent(&messages.EventConnectStateUpdate{
 		State: messages.ConnectStateDone,
 	})
+	close(s.started)
+	s.connectedAt = time.Now()
+	if s.resumed {
+		s.SendMessage("Reconnected")
+	}
 
 	doProxy := func(client bool) {
 		defer wg.Done()
@@ -319,6 +443,11 @@ func (s *Session) Run() error {
 	}
 
 	wg.Wait()
+	if s.lostServer != nil && s.reconnector != nil {
+		if delay, ok := s.reconnector.next(time.Since(s.connectedAt)); ok {
+			return s.holdForReconnect(delay)
+		}
+	}
 	err = context.Cause(s.ctx)
 	if !errors.Is(err, &errTransfer{}) {
 		if s.Client != nil {
@@ -412,7 +541,8 @@ func (s *Session) connectServer(ctx context.Context, rpHandler *resourcepacks.Re
 		DisconnectOnUnknownPackets: false,
 		ErrorLog:                   slog.Default(),
 		PacketFunc:                 s.packetFunc,
-		EnableClientCache:          s.settings.ClientCache,
+		// spectators cant request blobs so they need full chunks
+		EnableClientCache: s.settings.ClientCache && s.settings.Spectators == 0,
 		GetClientData: func() login.ClientData {
 			if s.withClient {
 				select {
@@ -452,6 +582,10 @@ func (s *Session) connectServer(ctx context.Context, rpHandler *resourcepacks.Re
 
 func (s *Session) connectClient(ctx context.Context, rpHandler *resourcepacks.ResourcePackHandler) (err error) {
 	clientPacketFunc := func(header packet.Header, payload []byte, src, dst net.Addr, timeReceived time.Time) {
+		if !s.isClientAddr(src, dst) {
+			// spectators have their own loop that drops what they send
+			return
+		}
 		pk, ok := DecodePacket(header, payload, s.Client.ShieldID())
 		if !ok {
 			return
@@ -487,6 +621,9 @@ func (s *Session) connectClient(ctx context.Context, rpHandler *resourcepacks.Re
 		ErrorLog:               slog.Default(),
 		PacketFunc:             clientPacketFunc,
 		OnClientData: func(c *minecraft.Conn) {
+			if c != s.Client {
+				return
+			}
 			s.clientData = c.ClientData()
 			ident := c.IdentityData()
 			s.handlers.PlayerDataModifier(s, &ident, &s.clientData)
@@ -494,6 +631,10 @@ func (s *Session) connectClient(ctx context.Context, rpHandler *resourcepacks.Re
 		},
 		EarlyConnHandler: func(c *minecraft.Conn) {
 			if s.Client != nil {
+				if s.spectators.count() < s.settings.Spectators {
+					s.spectators.joining.Add(1)
+					return
+				}
 				s.listener.Disconnect(c, "You are Already connected!")
 				return
 			}
@@ -516,6 +657,14 @@ func (s *Session) connectClient(ctx context.Context, rpHandler *resourcepacks.Re
 	var accepted = false
 	go func() {
 		<-ctx.Done()
+		if s.resumed {
+			select {
+			case <-s.clientConnecting:
+				// the client is held for the next attempt, Run closes the listener after
+				return
+			default:
+			}
+		}
 		if !accepted {
 			_ = s.listener.Close()
 		}
@@ -527,9 +676,98 @@ func (s *Session) connectClient(ctx context.Context, rpHandler *resourcepacks.Re
 	}
 	accepted = true
 	logrus.Info("Client Connected")
+	if s.settings.Spectators > 0 {
+		go s.acceptSpectators()
+	}
+	return nil
+}
+
+// isClientAddr returns if a packet from the listener is on the conn of the player and not of a spectator
+func (s *Session) isClientAddr(src, dst net.Addr) bool {
+	if s.Client == nil {
+		// spectators only join after the player
+		return true
+	}
+	addr := s.Client.RemoteAddr().String()
+	return src.String() == addr || dst.String() == addr
+}
+
+// listenReplayClient waits for a client to join that watches the replay
+func (s *Session) listenReplayClient(packs []resource.Pack) (err error) {
+	s.listener, err = minecraft.ListenConfig{
+		AuthenticationDisabled: true,
+		AllowUnknownPackets:    true,
+		StatusProvider:         minecraft.NewStatusProvider("Replay", "Bedrocktool"),
+		ErrorLog:               slog.Default(),
+		ResourcePacks:          packs,
+	}.Listen("raknet", s.settings.ListenAddress)
+	if err != nil {
+		return err
+	}
+
+	messages.SendEvent(&messages.EventConnectStateUpdate{
+		State: messages.ConnectStateListening,
+	})
+	logrus.Info(locale.Loc("listening_on", locale.Strmap{"Address": s.listener.Addr()}))
+	logrus.Info(locale.Loc("help_connect", nil))
+
+	var accepted atomic.Bool
+	go func() {
+		<-s.ctx.Done()
+		if !accepted.Load() {
+			_ = s.listener.Close()
+		}
+	}()
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return err
+	}
+	accepted.Store(true)
+	s.Client = conn.(*minecraft.Conn)
+	s.clientData = s.Client.ClientData()
+	logrus.Info("Client Connected")
 	return nil
 }
 
+func (s *Session) addReplayCommands(replay *pcap2.ReplayConnector) {
+	s.AddCommand(func(args []string) bool {
+		replay.Pause()
+		s.SendMessage("Replay paused")
+		return true
+	}, protocol.Command{
+		Name:        "pause",
+		Description: "pause the replay",
+	})
+
+	s.AddCommand(func(args []string) bool {
+		replay.Resume()
+		s.SendMessage("Replay resumed")
+		return true
+	}, protocol.Command{
+		Name:        "resume",
+		Description: "resume the replay",
+	})
+
+	s.AddCommand(func(args []string) bool {
+		if len(args) < 1 {
+			s.SendMessage(fmt.Sprintf("Replay speed is %g", replay.Speed()))
+			return true
+		}
+		speed, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			s.SendMessage(fmt.Sprintf("Invalid speed %s", args[0]))
+			return false
+		}
+		replay.SetSpeed(speed)
+		s.SendMessage(fmt.Sprintf("Replay speed set to %g", speed))
+		return true
+	}, protocol.Command{
+		Name:        "speed",
+		Description: "set the replay speed, 0 is as fast as possible",
+	})
+}
+
 func (s *Session) serverPrePlayHandler(conn *minecraft.Conn, pk packet.Packet, timeReceived time.Time) (handled bool, err error) {
 	switch pk := pk.(type) {
 	case *packet.BiomeDefinitionList:
@@ -556,12 +794,31 @@ func (s *Session) proxyLoop(ctx context.Context, toServer bool) (err error) {
 
 		pk, timeReceived, err := c1.ReadPacketWithTime()
 		if err != nil {
+			if !toServer && ctx.Err() == nil && !s.expectDisconnect {
+				s.lostServer = err
+				if _, ok := errors.Unwrap(err).(minecraft.DisconnectError); !ok {
+					s.lostServer = errors.New("lost connection to the server")
+				}
+			}
 			if errors.Is(err, net.ErrClosed) {
 				err = nil
 			}
 			return err
 		}
 		s.lastPacketTime.Store(&timeReceived)
+		if !toServer {
+			s.runLoopFuncs()
+		}
+
+		replay, isReplay := s.Server.(*pcap2.ReplayConnector)
+		if isReplay && toServer {
+			// the watching client only controls the replay with commands
+			_, err = s.commandHandlerPacketCB(pk, toServer, timeReceived, false)
+			if err != nil {
+				return err
+			}
+			continue
+		}
 
 		pkName := reflect.TypeOf(pk).String()
 
@@ -603,19 +860,20 @@ func (s *Session) proxyLoop(ctx context.Context, toServer bool) (err error) {
 		if forward == nil {
 			continue
 		}
+		if isReplay && replay.LastToServer() {
+			// packets the recorded player sent dont go to the watching client
+			continue
+		}
 
 		var transfer *packet.Transfer
 		switch _pk := pk.(type) {
 		case *packet.Transfer:
 			transfer = _pk
 			if s.Client != nil {
-				host, port, err := net.SplitHostPort(s.Client.ClientData().ServerAddress)
+				pk, err = s.transferToSelf()
 				if err != nil {
 					return err
 				}
-				// transfer to self
-				_port, _ := strconv.Atoi(port)
-				pk = &packet.Transfer{Address: host, Port: uint16(_port)}
 			}
 		case *packet.InventoryContent:
 			if _pk.StorageItem.Stack.NetworkID == -1 {
@@ -642,12 +900,82 @@ func (s *Session) proxyLoop(ctx context.Context, toServer bool) (err error) {
 			}
 		}
 
+		if s.settings.Spectators > 0 && pk != nil {
+			if !toServer {
+				s.spectators.observe(pk)
+				if mirrored(pk) {
+					s.spectators.broadcast(pk)
+				}
+			} else if input, ok := pk.(*packet.PlayerAuthInput); ok {
+				s.spectators.broadcast(&packet.MovePlayer{
+					EntityRuntimeID: s.Player.RuntimeID,
+					Position:        input.Position,
+					Pitch:           input.Pitch,
+					Yaw:             input.Yaw,
+					HeadYaw:         input.HeadYaw,
+					Mode:            packet.MoveModeNormal,
+				})
+			}
+		}
+
 		if transfer != nil {
 			return &errTransfer{transfer: transfer}
 		}
 	}
 }
 
+// transferToSelf makes a transfer packet that brings the client back to the proxy
+func (s *Session) transferToSelf() (*packet.Transfer, error) {
+	host, port, err := net.SplitHostPort(s.Client.ClientData().ServerAddress)
+	if err != nil {
+		return nil, err
+	}
+	_port, _ := strconv.Atoi(port)
+	return &packet.Transfer{Address: host, Port: uint16(_port)}, nil
+}
+
+// retryResume holds the client for another attempt when connecting again after losing the server failed,
+// err is returned as is when there are no attempts left
+func (s *Session) retryResume(err error) error {
+	if !s.resumed || s.reconnector == nil || s.reconnector.ctx.Err() != nil {
+		return err
+	}
+	delay, ok := s.reconnector.next(0)
+	if !ok {
+		return err
+	}
+	s.lostServer = err
+	return s.holdForReconnect(delay)
+}
+
+// holdForReconnect keeps the client in the world until it is time to connect again,
+// then sends it back to the proxy so it joins the next session
+func (s *Session) holdForReconnect(delay time.Duration) error {
+	s.log.Warnf("%s, reconnecting in %s", s.lostServer, delay)
+	if s.Client != nil {
+		go func() {
+			// nothing goes to the server while waiting
+			for {
+				if _, err := s.Client.ReadPacket(); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	if err := s.reconnector.wait(s, delay); err != nil {
+		return err
+	}
+	s.reconnecting = true
+	if s.Client != nil {
+		transfer, err := s.transferToSelf()
+		if err != nil {
+			return err
+		}
+		_ = s.Client.WritePacket(transfer)
+	}
+	return &errReconnect{cause: s.lostServer}
+}
+
 func (s *Session) packetFunc(header packet.Header, payload []byte, src, dst net.Addr, timeReceived time.Time) {
 	defer func() {
 		if errRec := recover(); errRec != nil {
@@ -756,20 +1084,34 @@ func (s *Session) commandHandlerPacketCB(pk packet.Packet, _ bool, _ time.Time,
 	case *packet.CommandRequest:
 		cmd := strings.Split(_pk.CommandLine, " ")
 		name := cmd[0][1:]
-		if h, ok := s.commands[name]; ok {
+		if h, ok := s.command(name); ok {
 			pk = nil
 			h.Exec(cmd[1:])
 		}
 	case *packet.AvailableCommands:
-		cmds := make([]protocol.Command, 0, len(s.commands))
-		for _, ic := range s.commands {
-			cmds = append(cmds, ic.Cmd)
-		}
-		_pk.Commands = append(_pk.Commands, cmds...)
+		_pk.Commands = append(_pk.Commands, s.commandList()...)
 	}
 	return pk, nil
 }
 
+// command returns the command with the name, it runs without the lock since it may add commands
+func (s *Session) command(name string) (ingameCommand, bool) {
+	s.commandsMu.Lock()
+	defer s.commandsMu.Unlock()
+	ic, ok := s.commands[name]
+	return ic, ok
+}
+
+func (s *Session) commandList() []protocol.Command {
+	s.commandsMu.Lock()
+	defer s.commandsMu.Unlock()
+	cmds := make([]protocol.Command, 0, len(s.commands))
+	for _, ic := range s.commands {
+		cmds = append(cmds, ic.Cmd)
+	}
+	return cmds
+}
+
 func (s *Session) playerPacketCB(pk packet.Packet, _ bool, _ time.Time, _ bool) (packet.Packet, error) {
 	if pk, ok := pk.(*packet.PacketViolationWarning); ok {
 		logrus.Infof("%+#v\n", pk)
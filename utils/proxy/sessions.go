@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+var (
+	sessionsMu sync.Mutex
+	sessions   []*Session
+)
+
+func addSession(s *Session) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	sessions = append(sessions, s)
+}
+
+func removeSession(s *Session) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	sessions = slices.DeleteFunc(sessions, func(s2 *Session) bool {
+		return s2 == s
+	})
+}
+
+// IngameCommands lists the in-game commands of all running sessions
+func IngameCommands() []protocol.Command {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	var cmds []protocol.Command
+	for _, s := range sessions {
+		for _, cmd := range s.commandList() {
+			if !slices.ContainsFunc(cmds, func(c protocol.Command) bool { return c.Name == cmd.Name }) {
+				cmds = append(cmds, cmd)
+			}
+		}
+	}
+	slices.SortFunc(cmds, func(a, b protocol.Command) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return cmds
+}
+
+// ExecIngameCommand runs an in-game command on every running session that has it,
+// on the loop of the session after its next packet. found is false if none of them do
+func ExecIngameCommand(name string, args []string) (found, ok bool) {
+	sessionsMu.Lock()
+	running := slices.Clone(sessions)
+	sessionsMu.Unlock()
+
+	ok = true
+	for _, s := range running {
+		ic, has := s.command(name)
+		if !has {
+			continue
+		}
+		found = true
+		var execOk bool
+		if !s.runOnLoop(func() { execOk = ic.Exec(args) }) || !execOk {
+			ok = false
+		}
+	}
+	return found, ok
+}
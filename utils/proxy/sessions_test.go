@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bedrock-tool/bedrocktool/utils/proxy/pcap2"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// writeTestReplay writes a capture that only has SetTime packets 50ms apart
+func writeTestReplay(t *testing.T, count int) string {
+	zipData := pcap2.EmptyZip()
+	var data bytes.Buffer
+	if err := pcap2.WriteHeader(&data, bytes.NewReader(zipData), int64(len(zipData))); err != nil {
+		t.Fatal(err)
+	}
+	start := time.UnixMilli(1700000000000)
+	for i := range count {
+		payload := bytes.NewBuffer(nil)
+		header := packet.Header{PacketID: packet.IDSetTime}
+		header.Write(payload)
+		pk := &packet.SetTime{Time: int32(i)}
+		pk.Marshal(protocol.NewWriter(payload, 0))
+		data.Write(pcap2.AppendPacket(nil, false, payload.Bytes(), start.Add(time.Duration(i)*50*time.Millisecond)))
+	}
+	path := filepath.Join(t.TempDir(), "replay.pcap2")
+	if err := os.WriteFile(path, data.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExecIngameCommandPausedReplay(t *testing.T) {
+	ctx := t.Context()
+	s := NewSession(ctx, ProxySettings{}, nil, nil, false)
+	replay, err := pcap2.CreateReplayConnector(ctx, writeTestReplay(t, 2), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Server = replay
+	replay.SetLoopFuncs(s.loopFuncs)
+	replay.SetSpeed(1)
+	s.addReplayCommands(replay)
+	addSession(s)
+	defer removeSession(s)
+
+	replay.Pause()
+
+	// reads like the loop of server packets does
+	read := make(chan error, 1)
+	go func() {
+		for range 2 {
+			if _, err := replay.ReadPacket(); err != nil {
+				read <- err
+				return
+			}
+		}
+		read <- nil
+	}()
+	select {
+	case err := <-read:
+		t.Fatalf("paused replay read on: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the read is blocked, the command has to run while it waits
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if found, ok := ExecIngameCommand("resume", nil); !found || !ok {
+			t.Errorf("resume: found %v ok %v", found, ok)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("resume command did not run while the replay was paused")
+	}
+	select {
+	case err := <-read:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay did not resume")
+	}
+}
@@ -0,0 +1,296 @@
+package proxy
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+	"github.com/sirupsen/logrus"
+)
+
+// spectatorRuntimeID is the entity id spectators get,
+// the watched player keeps its own id so packets about it apply to its mirrored entity
+const spectatorRuntimeID = 0x7fffffff
+
+type chunkKey struct {
+	dimension int32
+	pos       protocol.ChunkPos
+}
+
+// spectators are extra clients that get a read only copy of what the server sends,
+// the state they missed before joining is kept so they can be caught up
+type spectators struct {
+	mu      sync.Mutex
+	log     *logrus.Entry
+	conns   map[*minecraft.Conn]struct{}
+	joining atomic.Int32
+
+	dimension  int32
+	publisher  *packet.NetworkChunkPublisherUpdate
+	chunks     map[chunkKey][]packet.Packet
+	entities   map[uint64]packet.Packet
+	uniqueIDs  map[int64]uint64
+	playerList map[uuid.UUID]protocol.PlayerListEntry
+}
+
+func newSpectators() *spectators {
+	return &spectators{
+		log:        logrus.WithField("part", "Spectators"),
+		conns:      make(map[*minecraft.Conn]struct{}),
+		chunks:     make(map[chunkKey][]packet.Packet),
+		entities:   make(map[uint64]packet.Packet),
+		uniqueIDs:  make(map[int64]uint64),
+		playerList: make(map[uuid.UUID]protocol.PlayerListEntry),
+	}
+}
+
+func (sp *spectators) count() int {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return len(sp.conns) + int(sp.joining.Load())
+}
+
+// mirrored returns if a packet from the server should go to spectators,
+// packets that open ui or change what the player can do are only for the player
+func mirrored(pk packet.Packet) bool {
+	switch pk.(type) {
+	case *packet.ContainerOpen, *packet.ContainerClose, *packet.ModalFormRequest,
+		*packet.ServerSettingsResponse, *packet.NPCDialogue, *packet.ShowCredits,
+		*packet.Transfer, *packet.SetPlayerGameType, *packet.UpdateAbilities,
+		*packet.UpdateAdventureSettings, *packet.AvailableCommands, *packet.PlayStatus,
+		*packet.CameraInstruction, *packet.ClientCacheMissResponse, *packet.Disconnect:
+		return false
+	}
+	return true
+}
+
+func (sp *spectators) moveEntity(runtimeID uint64, pos [3]float32) {
+	switch pk := sp.entities[runtimeID].(type) {
+	case *packet.AddActor:
+		pk.Position = pos
+	case *packet.AddPlayer:
+		pk.Position = pos
+	case *packet.AddItemActor:
+		pk.Position = pos
+	}
+}
+
+// observe keeps track of the state a late spectator needs
+func (sp *spectators) observe(pk packet.Packet) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	switch pk := pk.(type) {
+	case *packet.ChangeDimension:
+		sp.dimension = pk.Dimension
+		clear(sp.entities)
+		clear(sp.uniqueIDs)
+	case *packet.NetworkChunkPublisherUpdate:
+		sp.publisher = pk
+		radius := int32(pk.Radius>>4) + 1
+		center := protocol.ChunkPos{pk.Position[0] >> 4, pk.Position[2] >> 4}
+		for key := range sp.chunks {
+			if key.dimension != sp.dimension {
+				continue
+			}
+			if abs(key.pos[0]-center[0]) > radius || abs(key.pos[1]-center[1]) > radius {
+				delete(sp.chunks, key)
+			}
+		}
+	case *packet.LevelChunk:
+		sp.chunks[chunkKey{pk.Dimension, pk.Position}] = []packet.Packet{pk}
+	case *packet.SubChunk:
+		key := chunkKey{pk.Dimension, protocol.ChunkPos{pk.Position[0], pk.Position[2]}}
+		if packets, ok := sp.chunks[key]; ok {
+			sp.chunks[key] = append(packets, pk)
+		}
+	case *packet.UpdateBlock:
+		key := chunkKey{sp.dimension, protocol.ChunkPos{pk.Position[0] >> 4, pk.Position[2] >> 4}}
+		if packets, ok := sp.chunks[key]; ok {
+			sp.chunks[key] = append(packets, pk)
+		}
+	case *packet.AddActor:
+		sp.entities[pk.EntityRuntimeID] = pk
+		sp.uniqueIDs[pk.EntityUniqueID] = pk.EntityRuntimeID
+	case *packet.AddPlayer:
+		sp.entities[pk.EntityRuntimeID] = pk
+		sp.uniqueIDs[pk.AbilityData.EntityUniqueID] = pk.EntityRuntimeID
+	case *packet.AddItemActor:
+		sp.entities[pk.EntityRuntimeID] = pk
+		sp.uniqueIDs[pk.EntityUniqueID] = pk.EntityRuntimeID
+	case *packet.RemoveActor:
+		if runtimeID, ok := sp.uniqueIDs[pk.EntityUniqueID]; ok {
+			delete(sp.entities, runtimeID)
+			delete(sp.uniqueIDs, pk.EntityUniqueID)
+		}
+	case *packet.MoveActorAbsolute:
+		sp.moveEntity(pk.EntityRuntimeID, pk.Position)
+	case *packet.MovePlayer:
+		sp.moveEntity(pk.EntityRuntimeID, pk.Position)
+	case *packet.PlayerList:
+		for _, entry := range pk.Entries {
+			if pk.ActionType == packet.PlayerListActionAdd {
+				sp.playerList[entry.UUID] = entry
+			} else {
+				delete(sp.playerList, entry.UUID)
+			}
+		}
+	}
+}
+
+func abs(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// catchUp sends everything a spectator missed, then adds it to the ones that get mirrored packets
+func (sp *spectators) catchUp(c *minecraft.Conn, player packet.Packet) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	var entries []protocol.PlayerListEntry
+	for _, entry := range sp.playerList {
+		entries = append(entries, entry)
+	}
+	packets := []packet.Packet{&packet.PlayerList{
+		ActionType: packet.PlayerListActionAdd,
+		Entries:    entries,
+	}}
+	if sp.publisher != nil {
+		packets = append(packets, sp.publisher)
+	}
+	for key, chunkPackets := range sp.chunks {
+		if key.dimension == sp.dimension {
+			packets = append(packets, chunkPackets...)
+		}
+	}
+	for _, pk := range sp.entities {
+		packets = append(packets, pk)
+	}
+	packets = append(packets, player)
+
+	for _, pk := range packets {
+		if err := c.WritePacket(pk); err != nil {
+			return err
+		}
+	}
+	sp.conns[c] = struct{}{}
+	return nil
+}
+
+func (sp *spectators) remove(c *minecraft.Conn) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	delete(sp.conns, c)
+}
+
+// broadcast sends a packet to every spectator
+func (sp *spectators) broadcast(pk packet.Packet) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	for c := range sp.conns {
+		if err := c.WritePacket(pk); err != nil {
+			sp.log.WithError(err).Warn("spectator write failed")
+			delete(sp.conns, c)
+			_ = c.Close()
+		}
+	}
+}
+
+func (sp *spectators) closeAll(reason string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	for c := range sp.conns {
+		_ = c.WritePacket(&packet.Disconnect{Message: reason})
+		_ = c.Close()
+	}
+	clear(sp.conns)
+}
+
+// acceptSpectators lets more clients join once the session is running
+func (s *Session) acceptSpectators() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.runSpectator(conn.(*minecraft.Conn))
+	}
+}
+
+// runSpectator spawns a spectator where the player is,
+// it can fly around freely and nothing it sends reaches the server
+func (s *Session) runSpectator(c *minecraft.Conn) {
+	defer c.Close()
+	joined := false
+	defer func() {
+		if !joined {
+			s.spectators.joining.Add(-1)
+		}
+	}()
+
+	select {
+	case <-s.started:
+	case <-s.ctx.Done():
+		return
+	}
+
+	gameData := s.gameData
+	gameData.EntityRuntimeID = spectatorRuntimeID
+	gameData.EntityUniqueID = spectatorRuntimeID
+	gameData.PlayerGameMode = packet.GameTypeSpectator
+	gameData.PlayerPosition = s.Player.Position
+	s.spectators.mu.Lock()
+	gameData.Dimension = s.spectators.dimension
+	s.spectators.mu.Unlock()
+
+	if s.dimensionData != nil {
+		_ = c.WritePacket(s.dimensionData)
+	}
+	if err := c.StartGameContext(s.ctx, gameData); err != nil {
+		s.spectators.log.WithError(err).Warn("spectator failed to spawn")
+		return
+	}
+
+	// nothing a spectator sends is used, it is read so the conn doesnt back up while catching up
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, err := c.ReadPacket(); err != nil {
+				return
+			}
+		}
+	}()
+
+	identity := s.Client.IdentityData()
+	playerUUID, _ := uuid.Parse(identity.Identity)
+	player := &packet.AddPlayer{
+		UUID:            playerUUID,
+		Username:        identity.DisplayName,
+		EntityRuntimeID: s.Player.RuntimeID,
+		Position:        s.Player.Position,
+		Pitch:           s.Player.Pitch,
+		Yaw:             s.Player.Yaw,
+		HeadYaw:         s.Player.HeadYaw,
+		GameType:        s.gameData.PlayerGameMode,
+		AbilityData: protocol.AbilityData{
+			EntityUniqueID: s.gameData.EntityUniqueID,
+		},
+	}
+	if err := s.spectators.catchUp(c, player); err != nil {
+		s.spectators.log.WithError(err).Warn("spectator failed to catch up")
+		return
+	}
+	s.spectators.joining.Add(-1)
+	joined = true
+	defer s.spectators.remove(c)
+
+	s.spectators.log.Infof("%s is spectating", c.IdentityData().DisplayName)
+	s.SendMessage(c.IdentityData().DisplayName + " is spectating")
+	<-done
+}
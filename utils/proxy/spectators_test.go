@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func TestMirrored(t *testing.T) {
+	for _, pk := range []packet.Packet{&packet.LevelChunk{}, &packet.MoveActorAbsolute{}, &packet.Text{}} {
+		if !mirrored(pk) {
+			t.Errorf("%T should go to spectators", pk)
+		}
+	}
+	for _, pk := range []packet.Packet{&packet.ModalFormRequest{}, &packet.Transfer{}, &packet.ContainerOpen{}, &packet.Disconnect{}} {
+		if mirrored(pk) {
+			t.Errorf("%T is only for the player", pk)
+		}
+	}
+}
+
+func TestSpectatorsObserveChunks(t *testing.T) {
+	sp := newSpectators()
+	near := protocol.ChunkPos{1, 1}
+	far := protocol.ChunkPos{20, 0}
+	sp.observe(&packet.LevelChunk{Position: near})
+	sp.observe(&packet.LevelChunk{Position: far})
+	sp.observe(&packet.SubChunk{Position: protocol.SubChunkPos{1, 0, 1}})
+	// sub chunks and blocks of chunks that were never sent are not kept
+	sp.observe(&packet.SubChunk{Position: protocol.SubChunkPos{5, 0, 5}})
+	sp.observe(&packet.UpdateBlock{Position: protocol.BlockPos{17, 64, 17}})
+	sp.observe(&packet.UpdateBlock{Position: protocol.BlockPos{-1, 64, -1}})
+
+	if n := len(sp.chunks[chunkKey{0, near}]); n != 3 {
+		t.Fatalf("expected the chunk with its sub chunk and block update, got %d packets", n)
+	}
+	if len(sp.chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(sp.chunks))
+	}
+
+	// chunks outside the radius of the publisher are dropped
+	sp.observe(&packet.NetworkChunkPublisherUpdate{Position: protocol.BlockPos{0, 64, 0}, Radius: 4 * 16})
+	if _, ok := sp.chunks[chunkKey{0, far}]; ok {
+		t.Fatal("chunk outside of the radius was kept")
+	}
+	if _, ok := sp.chunks[chunkKey{0, near}]; !ok {
+		t.Fatal("chunk inside of the radius was dropped")
+	}
+}
+
+func TestSpectatorsObserveEntities(t *testing.T) {
+	sp := newSpectators()
+	sp.observe(&packet.AddActor{EntityUniqueID: 10, EntityRuntimeID: 1})
+	sp.observe(&packet.AddPlayer{EntityRuntimeID: 2, AbilityData: protocol.AbilityData{EntityUniqueID: 20}})
+	sp.observe(&packet.MoveActorAbsolute{EntityRuntimeID: 1, Position: [3]float32{1, 2, 3}})
+	sp.observe(&packet.MovePlayer{EntityRuntimeID: 2, Position: [3]float32{4, 5, 6}})
+
+	if pos := sp.entities[1].(*packet.AddActor).Position; pos != [3]float32{1, 2, 3} {
+		t.Fatalf("actor wasnt moved, at %v", pos)
+	}
+	if pos := sp.entities[2].(*packet.AddPlayer).Position; pos != [3]float32{4, 5, 6} {
+		t.Fatalf("player wasnt moved, at %v", pos)
+	}
+
+	sp.observe(&packet.RemoveActor{EntityUniqueID: 10})
+	if _, ok := sp.entities[1]; ok || len(sp.entities) != 1 {
+		t.Fatalf("removed actor is still there, %d entities", len(sp.entities))
+	}
+
+	sp.observe(&packet.ChangeDimension{Dimension: 1})
+	if sp.dimension != 1 || len(sp.entities) != 0 || len(sp.uniqueIDs) != 0 {
+		t.Fatal("changing dimension should forget the entities")
+	}
+
+	id := uuid.New()
+	sp.observe(&packet.PlayerList{ActionType: packet.PlayerListActionAdd, Entries: []protocol.PlayerListEntry{{UUID: id}}})
+	if _, ok := sp.playerList[id]; !ok {
+		t.Fatal("player list entry wasnt added")
+	}
+	sp.observe(&packet.PlayerList{ActionType: packet.PlayerListActionRemove, Entries: []protocol.PlayerListEntry{{UUID: id}}})
+	if len(sp.playerList) != 0 {
+		t.Fatal("player list entry wasnt removed")
+	}
+}
@@ -0,0 +1,53 @@
+package schematic
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+)
+
+// WriteMCStructure writes the structure in the bedrock .mcstructure format that structure blocks load
+func (s *Structure) WriteMCStructure(w io.Writer) error {
+	palette := make([]any, 0, len(s.Palette))
+	for _, b := range s.Palette {
+		properties := b.Properties
+		if properties == nil {
+			properties = map[string]any{}
+		}
+		palette = append(palette, map[string]any{
+			"name":    b.Name,
+			"states":  properties,
+			"version": chunk.CurrentBlockVersion,
+		})
+	}
+
+	blockPositionData := make(map[string]any)
+	for pos, data := range s.BlockEntities {
+		blockPositionData[strconv.Itoa(s.index(pos))] = map[string]any{
+			"block_entity_data": data,
+		}
+	}
+
+	entities := make([]any, 0, len(s.Entities))
+	for _, ent := range s.Entities {
+		entities = append(entities, ent)
+	}
+
+	return nbt.NewEncoderWithEncoding(w, nbt.LittleEndian).Encode(map[string]any{
+		"format_version":         int32(1),
+		"size":                   []int32{int32(s.Size[0]), int32(s.Size[1]), int32(s.Size[2])},
+		"structure_world_origin": []int32{int32(s.Origin[0]), int32(s.Origin[1]), int32(s.Origin[2])},
+		"structure": map[string]any{
+			"block_indices": []any{s.Layers[0], s.Layers[1]},
+			"entities":      entities,
+			"palette": map[string]any{
+				"default": map[string]any{
+					"block_palette":       palette,
+					"block_position_data": blockPositionData,
+				},
+			},
+		},
+	})
+}
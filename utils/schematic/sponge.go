@@ -0,0 +1,102 @@
+package schematic
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"maps"
+	"reflect"
+
+	"github.com/bedrock-tool/bedrocktool/utils/anvil"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+)
+
+// byteArray makes an nbt byte array, slices are written as lists
+func byteArray(values []byte) any {
+	arr := reflect.New(reflect.ArrayOf(len(values), reflect.TypeFor[byte]())).Elem()
+	reflect.Copy(arr, reflect.ValueOf(values))
+	return arr.Interface()
+}
+
+// unsignedShort is how the sponge format stores sizes, a short with the bits of an unsigned short
+func unsignedShort(v int) int16 {
+	return int16(uint16(v))
+}
+
+// WriteSponge writes the structure as a version 2 sponge .schem with java blocks, for worldedit and other java tools
+func (s *Structure) WriteSponge(w io.Writer) error {
+	palette := map[string]any{"minecraft:air": int32(0)}
+	var blockData []byte
+	for y := range s.Size[1] {
+		for z := range s.Size[2] {
+			for x := range s.Size[0] {
+				pos := cube.Pos{s.Origin[0] + x, s.Origin[1] + y, s.Origin[2] + z}
+				i := s.index(pos)
+				state := "minecraft:air"
+				if idx := s.Layers[0][i]; idx >= 0 {
+					b := s.Palette[idx]
+					var waterlogged bool
+					if liquid := s.Layers[1][i]; liquid >= 0 {
+						name := s.Palette[liquid].Name
+						waterlogged = name == "minecraft:water" || name == "minecraft:flowing_water"
+					}
+					state = anvil.JavaBlockState(b.Name, b.Properties, s.BlockEntities[pos], waterlogged)
+				}
+				id, ok := palette[state]
+				if !ok {
+					id = int32(len(palette))
+					palette[state] = id
+				}
+				blockData = binary.AppendUvarint(blockData, uint64(id.(int32)))
+			}
+		}
+	}
+
+	blockEntities := []any{}
+	for pos, data := range s.BlockEntities {
+		be, ok := anvil.JavaBlockEntity(data, int32(pos[0]), int32(pos[1]), int32(pos[2]))
+		if !ok {
+			continue
+		}
+		be = maps.Clone(be)
+		be["Id"] = be["id"]
+		be["Pos"] = [3]int32{int32(pos[0] - s.Origin[0]), int32(pos[1] - s.Origin[1]), int32(pos[2] - s.Origin[2])}
+		for _, k := range []string{"id", "x", "y", "z", "keepPacked"} {
+			delete(be, k)
+		}
+		blockEntities = append(blockEntities, be)
+	}
+
+	entities := []any{}
+	for _, data := range s.Entities {
+		ent, ok := anvil.JavaEntity(data)
+		if !ok {
+			continue
+		}
+		p := ent["Pos"].([]float64)
+		ent["Id"] = ent["id"]
+		ent["Pos"] = []float64{p[0] - float64(s.Origin[0]), p[1] - float64(s.Origin[1]), p[2] - float64(s.Origin[2])}
+		delete(ent, "id")
+		entities = append(entities, ent)
+	}
+
+	zw := gzip.NewWriter(w)
+	err := nbt.NewEncoderWithEncoding(zw, nbt.BigEndian).Encode(map[string]any{
+		"Version":       int32(2),
+		"DataVersion":   int32(anvil.DataVersion),
+		"Width":         unsignedShort(s.Size[0]),
+		"Height":        unsignedShort(s.Size[1]),
+		"Length":        unsignedShort(s.Size[2]),
+		"Offset":        [3]int32{int32(s.Origin[0]), int32(s.Origin[1]), int32(s.Origin[2])},
+		"Palette":       palette,
+		"PaletteMax":    int32(len(palette)),
+		"BlockData":     byteArray(blockData),
+		"BlockEntities": blockEntities,
+		"Entities":      entities,
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
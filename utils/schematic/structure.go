@@ -0,0 +1,241 @@
+// Package schematic cuts regions out of worlds and writes them as .mcstructure or sponge .schem files.
+package schematic
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+)
+
+// Block is a bedrock block state in the palette
+type Block struct {
+	Name       string
+	Properties map[string]any
+}
+
+func (b Block) key() string {
+	// fmt sorts map keys
+	return fmt.Sprint(b.Name, b.Properties)
+}
+
+// Structure is a box of blocks, block entities and entities
+type Structure struct {
+	Origin cube.Pos
+	Size   [3]int
+
+	Palette []Block
+	// palette index per block for both layers, -1 where there is no block, index is (x*Size[1]+y)*Size[2]+z
+	Layers [2][]int32
+	// block entities by their world position
+	BlockEntities map[cube.Pos]map[string]any
+	// saved entity nbt, Pos is the world position
+	Entities []map[string]any
+
+	paletteIndex map[string]int32
+}
+
+// New makes an empty structure spanning from a to b, both included
+func New(a, b cube.Pos) *Structure {
+	origin := cube.Pos{min(a[0], b[0]), min(a[1], b[1]), min(a[2], b[2])}
+	size := [3]int{
+		max(a[0], b[0]) - origin[0] + 1,
+		max(a[1], b[1]) - origin[1] + 1,
+		max(a[2], b[2]) - origin[2] + 1,
+	}
+	s := &Structure{
+		Origin:        origin,
+		Size:          size,
+		BlockEntities: make(map[cube.Pos]map[string]any),
+		paletteIndex:  make(map[string]int32),
+	}
+	volume := size[0] * size[1] * size[2]
+	for layer := range s.Layers {
+		s.Layers[layer] = make([]int32, volume)
+		for i := range s.Layers[layer] {
+			s.Layers[layer][i] = -1
+		}
+	}
+	return s
+}
+
+func (s *Structure) String() string {
+	return fmt.Sprintf("%d %d %d (%dx%dx%d)", s.Origin[0], s.Origin[1], s.Origin[2], s.Size[0], s.Size[1], s.Size[2])
+}
+
+// Contains returns if the world position is inside the structure
+func (s *Structure) Contains(pos cube.Pos) bool {
+	for i := range 3 {
+		if pos[i] < s.Origin[i] || pos[i] >= s.Origin[i]+s.Size[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Structure) index(pos cube.Pos) int {
+	x, y, z := pos[0]-s.Origin[0], pos[1]-s.Origin[1], pos[2]-s.Origin[2]
+	return (x*s.Size[1]+y)*s.Size[2] + z
+}
+
+// SetBlock sets the block on a layer at a world position inside the structure
+func (s *Structure) SetBlock(pos cube.Pos, layer int, b Block) {
+	key := b.key()
+	idx, ok := s.paletteIndex[key]
+	if !ok {
+		idx = int32(len(s.Palette))
+		s.paletteIndex[key] = idx
+		s.Palette = append(s.Palette, b)
+	}
+	s.Layers[layer][s.index(pos)] = idx
+}
+
+// AddEntity adds the entity if its position is inside the structure
+func (s *Structure) AddEntity(data map[string]any) {
+	var pos cube.Pos
+	var coords []float64
+	switch p := data["Pos"].(type) {
+	case []float32:
+		for _, f := range p {
+			coords = append(coords, float64(f))
+		}
+	case []any:
+		for _, f := range p {
+			if f, ok := f.(float32); ok {
+				coords = append(coords, float64(f))
+			}
+		}
+	}
+	if len(coords) != 3 {
+		return
+	}
+	for i, f := range coords {
+		pos[i] = int(math.Floor(f))
+	}
+	if s.Contains(pos) {
+		s.Entities = append(s.Entities, data)
+	}
+}
+
+// ChunkLoader returns a chunk column and its block entities by world position, nil if it wasnt downloaded
+type ChunkLoader func(pos world.ChunkPos) (*chunk.Chunk, map[cube.Pos]map[string]any, error)
+
+// MaxVolume is the most blocks a structure can have
+const MaxVolume = 1 << 24
+
+// MaxSize is the longest a structure can be along one axis, schematics store the size as an unsigned short
+const MaxSize = 65535
+
+// Extract copies the blocks between a and b out of a world, blocks in chunks that are missing are left empty
+func Extract(blocks world.BlockRegistry, a, b cube.Pos, load ChunkLoader) (*Structure, error) {
+	volume := 1
+	for i := range 3 {
+		size := max(a[i], b[i]) - min(a[i], b[i]) + 1
+		if size > MaxSize {
+			return nil, fmt.Errorf("structure is %d blocks long, at most %d are allowed", size, MaxSize)
+		}
+		volume *= size
+	}
+	if volume > MaxVolume {
+		return nil, fmt.Errorf("structure has %d blocks, at most %d are allowed", volume, MaxVolume)
+	}
+	s := New(a, b)
+	minChunk := world.ChunkPos{int32(s.Origin[0] >> 4), int32(s.Origin[2] >> 4)}
+	maxChunk := world.ChunkPos{int32((s.Origin[0] + s.Size[0] - 1) >> 4), int32((s.Origin[2] + s.Size[2] - 1) >> 4)}
+
+	states := make(map[uint32]Block)
+	state := func(rid uint32) Block {
+		if b, ok := states[rid]; ok {
+			return b
+		}
+		name, properties, found := blocks.RuntimeIDToState(rid)
+		if !found {
+			name, properties = "minecraft:air", map[string]any{}
+		}
+		b := Block{Name: name, Properties: properties}
+		states[rid] = b
+		return b
+	}
+
+	for cx := minChunk[0]; cx <= maxChunk[0]; cx++ {
+		for cz := minChunk[1]; cz <= maxChunk[1]; cz++ {
+			ch, blockEntities, err := load(world.ChunkPos{cx, cz})
+			if err != nil {
+				return nil, err
+			}
+			if ch == nil {
+				continue
+			}
+			r := ch.Range()
+			for x := max(s.Origin[0], int(cx)*16); x < min(s.Origin[0]+s.Size[0], int(cx)*16+16); x++ {
+				for z := max(s.Origin[2], int(cz)*16); z < min(s.Origin[2]+s.Size[2], int(cz)*16+16); z++ {
+					for y := max(s.Origin[1], r[0]); y < min(s.Origin[1]+s.Size[1], r[1]+1); y++ {
+						pos := cube.Pos{x, y, z}
+						s.SetBlock(pos, 0, state(ch.Block(uint8(x&15), int16(y), uint8(z&15), 0)))
+						if b := state(ch.Block(uint8(x&15), int16(y), uint8(z&15), 1)); b.Name != "minecraft:air" {
+							s.SetBlock(pos, 1, b)
+						}
+					}
+				}
+			}
+			for pos, data := range blockEntities {
+				if s.Contains(pos) {
+					s.BlockEntities[pos] = data
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+// ParsePos reads a block position written as x,y,z
+func ParsePos(s string) (cube.Pos, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return cube.Pos{}, fmt.Errorf("position %q has to be x,y,z", s)
+	}
+	var pos cube.Pos
+	for i, part := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return cube.Pos{}, fmt.Errorf("position %q: %w", s, err)
+		}
+		pos[i] = v
+	}
+	return pos, nil
+}
+
+// Formats are the file extensions WriteFile can write
+var Formats = []string{".mcstructure", ".schem"}
+
+// WriteFile writes the structure in the format matching the extension of filename
+func (s *Structure) WriteFile(filename string) error {
+	var write func(w io.Writer) error
+	switch filepath.Ext(filename) {
+	case ".mcstructure":
+		write = s.WriteMCStructure
+	case ".schem":
+		write = s.WriteSponge
+	default:
+		return fmt.Errorf("unknown structure format %q, has to be one of %s", filepath.Ext(filename), strings.Join(Formats, " "))
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o777); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := write(f); err != nil {
+		return err
+	}
+	return f.Close()
+}